@@ -0,0 +1,94 @@
+package wadman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// addonListVersion specifies the maximum version of the addon list format this build of wadman supports
+const addonListVersion = 1
+
+// AddonListEntry describes an addon in a portable way, without any details specific to a single install.
+type AddonListEntry struct {
+	Id      string  `json:"id"`
+	Channel Channel `json:"channel,omitempty"`
+	Pin     string  `json:"pin,omitempty"`
+}
+
+// AddonList is a portable list of addons that can be shared between installs.
+type AddonList struct {
+	Addons []AddonListEntry
+}
+
+// NewAddonList creates a portable list describing the given addons. Addons installed from local files or directories
+// can't be used on other computers, so they are left out of the list and returned separately.
+func NewAddonList(addons []Addon) (*AddonList, []Addon) {
+	list := &AddonList{}
+	var skipped []Addon
+	for i := range addons {
+		if t := addons[i].Type(); t == TypeFile || t == TypeDirectory {
+			skipped = append(skipped, addons[i])
+			continue
+		}
+
+		list.Addons = append(list.Addons, AddonListEntry{
+			Id:      addons[i].ShortName(),
+			Channel: addons[i].Channel(),
+			Pin:     addons[i].Pin(),
+		})
+	}
+	return list, skipped
+}
+
+// ReadAddonList reads a JSON-encoded addon list from the given reader.
+func ReadAddonList(r io.Reader) (*AddonList, error) {
+	data := &struct {
+		Version int              `json:"version"`
+		Addons  []AddonListEntry `json:"addons"`
+	}{}
+	if err := json.NewDecoder(r).Decode(data); err != nil {
+		return nil, err
+	}
+
+	if data.Version > addonListVersion {
+		return nil, fmt.Errorf("addon list version %d requires a new version of wadman", data.Version)
+	}
+
+	// Entries may have been written by hand, so normalise them to match the values reported by installed addons
+	for i := range data.Addons {
+		channel, err := ParseChannel(string(data.Addons[i].Channel))
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry for %s: %v", data.Addons[i].Id, err)
+		}
+		data.Addons[i].Channel = channel
+
+		addon, err := NewAddon(data.Addons[i].Id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry for %s: %v", data.Addons[i].Id, err)
+		}
+		data.Addons[i].Id = addon.ShortName()
+	}
+
+	return &AddonList{Addons: data.Addons}, nil
+}
+
+// Write encodes the addon list as JSON to the given writer.
+func (l *AddonList) Write(w io.Writer) error {
+	addons := l.Addons
+	if addons == nil {
+		addons = []AddonListEntry{}
+	}
+
+	data := &struct {
+		Version int              `json:"version"`
+		Addons  []AddonListEntry `json:"addons"`
+	}{
+		addonListVersion,
+		addons,
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
@@ -1,9 +1,11 @@
 package wadman
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"github.com/csmith/wadman/wow"
-	"io"
+	"strings"
 	"time"
 )
 
@@ -13,37 +15,142 @@ const (
 	TypeUnspecified  AddonType = ""
 	TypeCurseForge   AddonType = "curse"
 	TypeWowInterface AddonType = "wowi"
+	TypeFile         AddonType = "file"
+	TypeDirectory    AddonType = "dir"
+	TypeGit          AddonType = "git"
+	TypeWago         AddonType = "wago"
 )
 
-func (t AddonType) NewInstance() (Addon, error) {
-	switch t {
-	case TypeUnspecified:
-		// For compatibility with old configs, if the type field is missing default to curseforge
-		return NewCurseForgeAddon(0), nil
-	case TypeCurseForge:
-		return NewCurseForgeAddon(0), nil
-	case TypeWowInterface:
-		return NewWowInterfaceAddon(0), nil
+// Channel determines which kinds of release an addon will be updated to.
+type Channel string
+
+const (
+	// ChannelDefault uses the source's default behaviour, which accepts both releases and betas.
+	ChannelDefault Channel = ""
+	ChannelRelease Channel = "release"
+	ChannelBeta    Channel = "beta"
+	ChannelAlpha   Channel = "alpha"
+)
+
+// ParseChannel converts the given string to a Channel, returning an error if it is not recognised.
+func ParseChannel(s string) (Channel, error) {
+	switch c := Channel(strings.ToLower(s)); c {
+	case ChannelDefault, ChannelRelease, ChannelBeta, ChannelAlpha:
+		return c, nil
 	default:
-		return nil, fmt.Errorf("unknown addon type: %s", t)
+		return ChannelDefault, fmt.Errorf("unknown channel: %s", s)
 	}
 }
 
 type Addon interface {
+	Type() AddonType
 	ShortName() string
 	DisplayName() string
 	Dirs() []string
 	CurrentVersion() string
 	LastUpdated() time.Time
 
-	Update(w *wow.Install, debug io.Writer, force bool) (updated bool, err error)
+	// Channel returns the release channel the addon is updated from.
+	Channel() Channel
+	SetChannel(channel Channel)
+	// Pin returns the ID of the file the addon is pinned to, or an empty string if it is not pinned. Pinned addons
+	// are never updated to a different file.
+	Pin() string
+	SetPin(fileId string)
+
+	// Locked returns a lockfile entry describing the exact file that is currently installed.
+	Locked() LockEntry
+	// Install deploys the given release, replacing any version of the addon that is already installed. If the
+	// context is cancelled the existing version is left in place. If progress is non-nil it is called while the
+	// release is being downloaded.
+	Install(ctx context.Context, w *wow.Install, release *Release, progress wow.ProgressFunc) error
+}
+
+// Update checks for a newer release of the addon and installs it. If force is true, the latest release is installed
+// even if it is already present. Progress is reported to the observer, finishing with either a DoneEvent or a
+// FailedEvent.
+func Update(ctx context.Context, addon Addon, w *wow.Install, observer Observer, force bool) (updated bool, err error) {
+	defer func() {
+		if err != nil {
+			observer.Notify(FailedEvent{Addon: addon, Err: err})
+		} else {
+			observer.Notify(DoneEvent{Addon: addon, Updated: updated, Pinned: addon.Pin() != ""})
+		}
+	}()
+
+	var release *Release
+	if addon.Pin() != "" {
+		// Pinned addons are never moved to a different file, but the pinned file is reinstalled if forced
+		if !force {
+			return false, nil
+		}
+		release = addon.Locked().Release()
+	} else {
+		latest, newer, err := CheckForUpdate(ctx, addon, w, observer)
+		if err != nil {
+			return false, err
+		}
+
+		if !force && !newer {
+			return false, nil
+		}
+		release = latest
+	}
+
+	if err := InstallRelease(ctx, addon, w, release, observer); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// InstallRelease installs a specific release of the addon, reporting download progress and extraction to the
+// observer.
+func InstallRelease(ctx context.Context, addon Addon, w *wow.Install, release *Release, observer Observer) error {
+	progress := func(downloaded int64, total int64) {
+		observer.Notify(DownloadProgressEvent{Addon: addon, Url: release.Url, Bytes: downloaded, Total: total})
+	}
+
+	if err := addon.Install(ctx, w, release, progress); err != nil {
+		return err
+	}
+
+	observer.Notify(ExtractedEvent{Addon: addon, Directories: addon.Dirs()})
+	return nil
+}
+
+// CheckForUpdate resolves the latest release of the addon for the given install without installing it, and reports
+// whether it differs from the currently installed file.
+func CheckForUpdate(ctx context.Context, addon Addon, w *wow.Install, observer Observer) (latest *Release, newer bool, err error) {
+	provider, err := ProviderFor(addon.Type())
+	if err != nil {
+		return nil, false, err
+	}
+
+	observer.Notify(CheckingEvent{Addon: addon})
+	latest, err = provider.Resolve(ctx, addon, w, observer)
+	if err != nil {
+		return nil, false, err
+	}
+
+	newer = latest.FileId != addon.Locked().FileId
+	observer.Notify(ResolvedEvent{Addon: addon, Release: latest, Newer: newer})
+	return latest, newer, nil
 }
 
 type BaseAddon struct {
-	Type        AddonType `json:"type"`
+	AddonType   AddonType `json:"type"`
 	Directories []string  `json:"directories"`
 	Version     string    `json:"version"`
 	LastUpdate  time.Time `json:"last_update"`
+	DownloadUrl string    `json:"download_url,omitempty"`
+	Checksum    string    `json:"checksum,omitempty"`
+
+	UpdateChannel Channel `json:"channel,omitempty"`
+	PinnedFile    string  `json:"pin,omitempty"`
+}
+
+func (a *BaseAddon) Type() AddonType {
+	return a.AddonType
 }
 
 func (a *BaseAddon) Dirs() []string {
@@ -57,3 +164,63 @@ func (a *BaseAddon) CurrentVersion() string {
 func (a *BaseAddon) LastUpdated() time.Time {
 	return a.LastUpdate
 }
+
+func (a *BaseAddon) Channel() Channel {
+	return a.UpdateChannel
+}
+
+func (a *BaseAddon) SetChannel(channel Channel) {
+	a.UpdateChannel = channel
+}
+
+func (a *BaseAddon) Pin() string {
+	return a.PinnedFile
+}
+
+func (a *BaseAddon) SetPin(fileId string) {
+	a.PinnedFile = fileId
+}
+
+// LockEntry creates a lockfile entry for the addon using the given names and source-specific file ID.
+func (a *BaseAddon) LockEntry(id string, name string, fileId string) LockEntry {
+	return LockEntry{
+		Id:       id,
+		Name:     name,
+		FileId:   fileId,
+		Version:  a.Version,
+		Url:      a.DownloadUrl,
+		Checksum: a.Checksum,
+	}
+}
+
+// Deploy downloads and deploys the given release, replacing any existing directories belonging to the addon and
+// updating the common metadata. If the release has a checksum, the download is verified against it before the
+// existing directories are touched. If the context is cancelled, the existing directories are left in place. Addon
+// implementations should call this from their Install method, and then update any source-specific metadata.
+func (a *BaseAddon) Deploy(ctx context.Context, w *wow.Install, release *Release, progress wow.ProgressFunc) error {
+	if release.Url == "" {
+		return fmt.Errorf("no download address for file %s", release.FileId)
+	}
+
+	b, checksum, err := wow.DownloadAddon(ctx, release.Url, release.Checksum, progress)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := w.ReplaceAddons(ctx, bytes.NewReader(b), a.Directories)
+	if err != nil {
+		return err
+	}
+
+	a.deployed(release, dirs, checksum)
+	return nil
+}
+
+// deployed updates the common metadata after the given release has been deployed to the given directories.
+func (a *BaseAddon) deployed(release *Release, dirs []string, checksum string) {
+	a.Directories = dirs
+	a.Version = release.Version
+	a.DownloadUrl = release.Url
+	a.Checksum = checksum
+	a.LastUpdate = time.Now()
+}
@@ -0,0 +1,291 @@
+package wadman
+
+import (
+	"context"
+	"fmt"
+	"github.com/csmith/wadman/wagoio"
+	"github.com/csmith/wadman/wow"
+	"github.com/csmith/wadman/wow/savedvars"
+	"github.com/csmith/wadman/wow/toc"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WagoIoClient is used to check Wago.io for updates to WeakAuras and Plater imports. Its API key only needs to be set
+// to check private imports, and its base URL may be changed to use a different server.
+var WagoIoClient = wagoio.NewClient("")
+
+// CompanionAddon is the name of the addon that update data for WeakAuras and Plater is written to. WeakAuras and
+// Plater both read data from this addon when they load, and offer to update any imports it lists.
+const CompanionAddon = "WeakAurasCompanion"
+
+// defaultCompanionInterface is the interface version used in the companion addon's TOC file if it can't be copied
+// from the installed version of WeakAuras.
+const defaultCompanionInterface = "100002"
+
+// wagoUrlPattern matches the addresses stored by WeakAuras and Plater for imports from Wago.io, capturing the slug
+// and the (optional) version.
+var wagoUrlPattern = regexp.MustCompile(`^https?://(?:www\.)?wago\.io/([^/?#]+)(?:/(\d+))?`)
+
+// Aura is a WeakAura, Plater script or Plater profile that was imported from Wago.io.
+type Aura struct {
+	// Kind is the type of import, either wagoio.WeakAuras or wagoio.Plater.
+	Kind string
+	// Slug identifies the import on Wago.io.
+	Slug string
+	// Name is the name of the import in game.
+	Name string
+	// Version is the Wago.io version that is installed. If an import is installed several times (e.g. on different
+	// accounts), this is the oldest version.
+	Version int64
+	// Ignored indicates the user has asked to never be told about updates to the import.
+	Ignored bool
+	// SkipVersion is a version that the user has asked not to be told about.
+	SkipVersion int64
+}
+
+// AuraUpdate is a newer version of an Aura that is available on Wago.io.
+type AuraUpdate struct {
+	Aura    *Aura
+	Import  *wagoio.Import
+	Encoded string
+}
+
+// FindAuras reads the SavedVariables of every account in the WoW install and returns the WeakAuras, Plater scripts
+// and Plater profiles that were imported from Wago.io. Imports are de-duplicated by their slug.
+func FindAuras(install *wow.Install) ([]*Aura, error) {
+	accounts, err := install.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	auras := make(map[string]*Aura)
+	var order []string
+	add := func(kind string, name string, data *savedvars.Table) {
+		matches := wagoUrlPattern.FindStringSubmatch(data.String("url"))
+		if matches == nil {
+			return
+		}
+
+		version := data.Int("version")
+		if version == 0 && matches[2] != "" {
+			version, _ = strconv.ParseInt(matches[2], 10, 64)
+		}
+
+		key := fmt.Sprintf("%s:%s", kind, matches[1])
+		aura, ok := auras[key]
+		if !ok {
+			aura = &Aura{Kind: kind, Slug: matches[1], Name: name, Version: version}
+			auras[key] = aura
+			order = append(order, key)
+		} else if version < aura.Version {
+			aura.Version = version
+		}
+
+		aura.Ignored = aura.Ignored || data.Bool("ignoreWagoUpdate")
+		if skip := data.Int("skipWagoUpdate"); skip > aura.SkipVersion {
+			aura.SkipVersion = skip
+		}
+	}
+
+	for _, account := range accounts {
+		weakAuras, err := loadSavedVariables(install, account.Name, "WeakAuras")
+		if err != nil {
+			return nil, err
+		}
+
+		displays := weakAuras.Table("WeakAurasSaved").Table("displays")
+		for _, entry := range displays.Entries {
+			if data, ok := entry.Value.(*savedvars.Table); ok {
+				add(wagoio.WeakAuras, data.String("id"), data)
+			}
+		}
+
+		plater, err := loadSavedVariables(install, account.Name, "Plater")
+		if err != nil {
+			return nil, err
+		}
+
+		profiles := plater.Table("PlaterDB").Table("profiles")
+		for _, entry := range profiles.Entries {
+			profile, ok := entry.Value.(*savedvars.Table)
+			if !ok {
+				continue
+			}
+
+			name, _ := entry.Key.(string)
+			add(wagoio.Plater, name, profile)
+			for _, field := range []string{"script_data", "hook_data"} {
+				for _, v := range profile.Table(field).Values() {
+					if data, ok := v.(*savedvars.Table); ok {
+						add(wagoio.Plater, data.String("Name"), data)
+					}
+				}
+			}
+		}
+	}
+
+	var res []*Aura
+	for _, key := range order {
+		res = append(res, auras[key])
+	}
+	return res, nil
+}
+
+// loadSavedVariables parses the account-wide SavedVariables for the given addon. An empty file is returned if the
+// addon has no SavedVariables.
+func loadSavedVariables(install *wow.Install, account string, addon string) (*savedvars.File, error) {
+	file, err := savedvars.Load(install.SavedVariablesPath(account, addon))
+	if os.IsNotExist(err) {
+		return &savedvars.File{}, nil
+	}
+	return file, err
+}
+
+// CheckAuras queries Wago.io for newer versions of the given auras, returning an update (including the new import
+// string) for each one that is out of date. Auras the user has chosen to ignore, or whose latest version they have
+// chosen to skip, are not included.
+func CheckAuras(ctx context.Context, auras []*Aura) ([]*AuraUpdate, error) {
+	var updates []*AuraUpdate
+	for _, kind := range []string{wagoio.WeakAuras, wagoio.Plater} {
+		bySlug := make(map[string]*Aura)
+		var slugs []string
+		for _, aura := range auras {
+			if aura.Kind == kind {
+				bySlug[aura.Slug] = aura
+				slugs = append(slugs, aura.Slug)
+			}
+		}
+
+		if len(slugs) == 0 {
+			continue
+		}
+
+		imports, err := WagoIoClient.Check(ctx, kind, slugs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range imports {
+			aura, ok := bySlug[i.Slug]
+			if !ok {
+				// Imports may be identified by their ID rather than their slug
+				aura, ok = bySlug[i.Id]
+			}
+			if !ok || aura.Ignored || i.Version <= aura.Version || i.Version == aura.SkipVersion {
+				continue
+			}
+
+			encoded, err := WagoIoClient.Encoded(ctx, i.Id)
+			if err != nil {
+				return nil, fmt.Errorf("unable to download %s: %v", aura.Slug, err)
+			}
+
+			updates = append(updates, &AuraUpdate{Aura: aura, Import: i, Encoded: encoded})
+		}
+	}
+	return updates, nil
+}
+
+// WriteCompanion creates or replaces the companion addon so that it contains the given updates. It should be called
+// even if there are no updates, so that any previous updates that have since been installed are removed.
+func WriteCompanion(install *wow.Install, updates []*AuraUpdate) error {
+	tocInterface := defaultCompanionInterface
+	if t, err := toc.ForAddon(install, "WeakAuras"); err == nil && len(t.Interface) > 0 {
+		tocInterface = t.InterfaceString()
+	}
+
+	data, err := companionData(updates)
+	if err != nil {
+		return err
+	}
+
+	return install.WriteAddon(CompanionAddon, map[string][]byte{
+		fmt.Sprintf("%s.toc", CompanionAddon): []byte(companionToc(tocInterface)),
+		"data.lua":                            data,
+		"init.lua":                            []byte(companionInit),
+	})
+}
+
+func companionToc(tocInterface string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("## Interface: %s", tocInterface),
+		"## Title: WeakAuras Companion",
+		"## Author: wadman",
+		"## Notes: Update data for WeakAuras and Plater, generated by wadman",
+		"## DefaultState: Enabled",
+		"## LoadOnDemand: 0",
+		"## OptionalDeps: WeakAuras, Plater",
+		"",
+		"data.lua",
+		"init.lua",
+		"",
+	}, "\n")
+}
+
+// companionData generates the Lua file containing the update data, in the format WeakAuras and Plater expect.
+func companionData(updates []*AuraUpdate) ([]byte, error) {
+	data := &savedvars.Table{}
+	for _, kind := range []string{wagoio.WeakAuras, wagoio.Plater} {
+		var kindUpdates []*AuraUpdate
+		for _, u := range updates {
+			if u.Aura.Kind == kind {
+				kindUpdates = append(kindUpdates, u)
+			}
+		}
+		sort.Slice(kindUpdates, func(i, j int) bool {
+			return kindUpdates[i].Aura.Slug < kindUpdates[j].Aura.Slug
+		})
+
+		slugs := &savedvars.Table{}
+		for _, u := range kindUpdates {
+			slug := &savedvars.Table{}
+			slug.Set("name", u.Import.Name)
+			slug.Set("author", u.Import.Username)
+			slug.Set("encoded", u.Encoded)
+			slug.Set("wagoVersion", strconv.FormatInt(u.Import.Version, 10))
+			slug.Set("wagoSemver", u.Import.VersionString)
+			slug.Set("versionNote", u.Import.Changelog.Text)
+			slug.Set("source", "Wago")
+			slugs.Set(u.Aura.Slug, slug)
+		}
+
+		section := &savedvars.Table{}
+		section.Set("slugs", slugs)
+		section.Set("stash", &savedvars.Table{})
+		data.Set(companionKey(kind), section)
+	}
+
+	file := &savedvars.File{}
+	file.Set("WeakAurasCompanionData", data)
+	file.Vars[0].Comments = []string{"-- file generated automatically by wadman"}
+	return file.Bytes()
+}
+
+// companionKey returns the key used for the given kind of import in the companion data.
+func companionKey(kind string) string {
+	if kind == wagoio.Plater {
+		return "Plater"
+	}
+	return "WeakAuras"
+}
+
+// companionInit passes the update data to WeakAuras and Plater once the companion addon has loaded.
+const companionInit = `-- file generated automatically by wadman
+local loadedFrame = CreateFrame("FRAME")
+loadedFrame:RegisterEvent("ADDON_LOADED")
+loadedFrame:SetScript("OnEvent", function(_, _, addonName)
+  if addonName == "WeakAurasCompanion" then
+    if WeakAuras and WeakAuras.AddCompanionData and WeakAurasCompanionData and WeakAurasCompanionData.WeakAuras then
+      WeakAuras.AddCompanionData(WeakAurasCompanionData.WeakAuras)
+    end
+    if Plater and Plater.AddCompanionData and WeakAurasCompanionData and WeakAurasCompanionData.Plater then
+      Plater.AddCompanionData(WeakAurasCompanionData.Plater)
+    end
+    loadedFrame:UnregisterEvent("ADDON_LOADED")
+  end
+end)
+`
@@ -0,0 +1,124 @@
+package wadman
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Changelog retrieves the changelog for the given release of an addon from its provider, converted to plain text
+// suitable for displaying in a terminal. An empty string is returned if the source doesn't provide a changelog.
+func Changelog(ctx context.Context, addon Addon, release *Release) (string, error) {
+	provider, err := ProviderFor(addon.Type())
+	if err != nil {
+		return "", err
+	}
+
+	changelog, err := provider.Changelog(ctx, addon, release)
+	if err != nil {
+		return "", err
+	}
+
+	return ChangelogText(changelog), nil
+}
+
+var (
+	htmlTag        = regexp.MustCompile(`(?s)<(/?)([a-zA-Z][a-zA-Z0-9]*)[^>]*?(/?)>`)
+	htmlComment    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlInvisible  = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlWhitespace = regexp.MustCompile(`[ \t\r\n]+`)
+	blankLines     = regexp.MustCompile(`\n{3,}`)
+)
+
+// ChangelogText converts a changelog that may contain HTML into readable plain text. Block-level elements are
+// separated by blank lines, list items are prefixed with bullets, and entities are decoded. Text that doesn't look
+// like HTML is only trimmed.
+func ChangelogText(changelog string) string {
+	if !htmlTag.MatchString(changelog) {
+		return strings.TrimSpace(strings.ReplaceAll(changelog, "\r\n", "\n"))
+	}
+
+	changelog = htmlComment.ReplaceAllString(changelog, "")
+	changelog = htmlInvisible.ReplaceAllString(changelog, "")
+
+	var (
+		out    strings.Builder
+		depth  int
+		inPre  bool
+		offset int
+	)
+
+	text := func(s string) {
+		if !inPre {
+			s = htmlWhitespace.ReplaceAllString(s, " ")
+			if current := out.String(); current == "" || strings.HasSuffix(current, "\n") || strings.HasSuffix(current, "• ") {
+				s = strings.TrimLeft(s, " ")
+			}
+		}
+		out.WriteString(html.UnescapeString(s))
+	}
+
+	// newline ensures the output ends with at least the given number of line breaks
+	newline := func(count int) {
+		current := strings.TrimRight(out.String(), " ")
+		out.Reset()
+		out.WriteString(current)
+		for i := len(current) - len(strings.TrimRight(current, "\n")); i < count; i++ {
+			out.WriteString("\n")
+		}
+	}
+
+	for _, m := range htmlTag.FindAllStringSubmatchIndex(changelog, -1) {
+		text(changelog[offset:m[0]])
+		offset = m[1]
+
+		closing := m[3] > m[2]
+		name := strings.ToLower(changelog[m[4]:m[5]])
+		switch name {
+		case "br":
+			newline(1)
+		case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "table":
+			newline(2)
+		case "tr":
+			newline(1)
+		case "td", "th":
+			if !closing {
+				out.WriteString(" ")
+			}
+		case "hr":
+			newline(1)
+			out.WriteString("----")
+			newline(2)
+		case "pre":
+			inPre = !closing
+			newline(2)
+		case "ul", "ol":
+			if closing {
+				if depth > 0 {
+					depth--
+				}
+			} else {
+				depth++
+			}
+			newline(1)
+		case "li":
+			if !closing {
+				newline(1)
+				indent := depth - 1
+				if indent < 0 {
+					indent = 0
+				}
+				out.WriteString(strings.Repeat("  ", indent))
+				out.WriteString("• ")
+			}
+		}
+	}
+	text(changelog[offset:])
+
+	lines := strings.Split(out.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
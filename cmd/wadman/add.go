@@ -4,15 +4,15 @@ import (
 	"fmt"
 	"github.com/csmith/wadman"
 	"github.com/spf13/cobra"
-	"io/ioutil"
-	"strconv"
-	"strings"
 )
 
 func init() {
 	rootCommand.AddCommand(addCommand)
+	addCommand.Flags().BoolVar(&link, "link", false, "Symlink addons from local directories instead of copying them")
 }
 
+var link bool
+
 var addCommand = &cobra.Command{
 	Use:   "add <id [id [id [...]]]>",
 	Short: "Download and install new addons",
@@ -21,33 +21,87 @@ var addCommand = &cobra.Command{
 		defer saveConfig()
 
 		for i := range args {
-			var addon wadman.Addon
-			if strings.HasPrefix(args[i], "wowi:") {
-				target, _ := strconv.Atoi(strings.TrimPrefix(args[i], "wowi:"))
-				addon = wadman.NewWowInterfaceAddon(target)
-			} else if strings.HasPrefix(args[i], "curse:") {
-				target, _ := strconv.Atoi(strings.TrimPrefix(args[i], "curse:"))
-				addon = wadman.NewCurseForgeAddon(target)
-			} else {
-				fmt.Printf("%s: Unrecognised addon type. Did you mean curse:%[1]s or wowi:%[1]s?\n", args[i])
-				continue
+			if interrupted() {
+				break
 			}
 
-			if addonExists(addon.ShortName()) {
-				fmt.Printf("%s: Addon is already installed.\n", args[i])
+			addon, err := wadman.NewAddon(args[i])
+			if err != nil {
+				fmt.Printf("%s: %v\n", args[i], err)
+				addonFailed()
 				continue
 			}
 
-			if _, err := addon.Update(install, ioutil.Discard, false); err != nil {
-				fmt.Printf("Unable to install addon %s: %v\n", args[i], err)
-			} else {
-				fmt.Printf("Installed addon '%s' version %s\n", addon.DisplayName(), addon.CurrentVersion())
-				config.Addons = append(config.Addons, addon)
+			if d, ok := addon.(*wadman.DirectoryAddon); ok {
+				d.Link = link
 			}
+
+			addAddon(args[i], addon)
 		}
 	},
 }
 
+// addAddon installs the given addon and adds it to the config, unless it is already installed. Returns true if the
+// addon was installed.
+func addAddon(arg string, addon wadman.Addon) bool {
+	if addonExists(addon.ShortName()) {
+		fmt.Printf("%s: Addon is already installed.\n", arg)
+		return false
+	}
+
+	if _, err := wadman.Update(ctx, addon, install, nil, false); err != nil {
+		fmt.Printf("Unable to install addon %s: %v\n", arg, err)
+		addonFailed()
+		return false
+	}
+
+	fmt.Printf("Installed addon '%s' version %s\n", addon.DisplayName(), addon.CurrentVersion())
+	config.Addons = append(config.Addons, addon)
+	return true
+}
+
+// addPinnedAddon installs the given file of the addon and adds it to the config pinned to that file, unless it is
+// already installed. If the file ID is empty the latest release is installed instead. Returns true if the addon was
+// installed.
+func addPinnedAddon(arg string, addon wadman.Addon, fileId string) bool {
+	if fileId == "" {
+		return addAddon(arg, addon)
+	}
+
+	if addonExists(addon.ShortName()) {
+		fmt.Printf("%s: Addon is already installed.\n", arg)
+		return false
+	}
+
+	if err := pinAddon(addon, fileId); err != nil {
+		fmt.Printf("Unable to install addon %s: %v\n", arg, err)
+		addonFailed()
+		return false
+	}
+
+	fmt.Printf("Installed addon '%s' version %s (pinned to file %s)\n", addon.DisplayName(), addon.CurrentVersion(), fileId)
+	config.Addons = append(config.Addons, addon)
+	return true
+}
+
+// pinAddon pins the addon to the given file ID, installing that file first if it isn't the one that is installed. The
+// addon is unpinned if the ID is empty.
+func pinAddon(addon wadman.Addon, fileId string) error {
+	if fileId != "" && addon.Locked().FileId != fileId {
+		release, err := wadman.FindRelease(ctx, addon, fileId)
+		if err != nil {
+			return err
+		}
+
+		if err := wadman.InstallRelease(ctx, addon, install, release, nil); err != nil {
+			return err
+		}
+	}
+
+	addon.SetPin(fileId)
+	return nil
+}
+
 func addonExists(shortName string) bool {
 	for i := range config.Addons {
 		if config.Addons[i].ShortName() == shortName {
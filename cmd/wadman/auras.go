@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+)
+
+func init() {
+	rootCommand.AddCommand(aurasCommand)
+	aurasCommand.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Check for updates without writing them to the companion addon")
+	aurasCommand.Flags().BoolVarP(&showChangelogs, "changelog", "c", false, "Show the changelog for each available update")
+}
+
+type auraRecord struct {
+	Type             string `json:"type" yaml:"type"`
+	Slug             string `json:"slug" yaml:"slug"`
+	Name             string `json:"name" yaml:"name"`
+	InstalledVersion int64  `json:"installed_version" yaml:"installed_version"`
+	LatestVersion    int64  `json:"latest_version,omitempty" yaml:"latest_version,omitempty"`
+	LatestSemver     string `json:"latest_semver,omitempty" yaml:"latest_semver,omitempty"`
+	Outdated         bool   `json:"outdated" yaml:"outdated"`
+	Ignored          bool   `json:"ignored" yaml:"ignored"`
+	Changelog        string `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+}
+
+var aurasCommand = &cobra.Command{
+	Use:   "auras",
+	Short: "Check WeakAuras and Plater imports from Wago.io for updates",
+	Long: "Check WeakAuras and Plater imports from Wago.io for updates, and write any that are found to the " +
+		wadman.CompanionAddon + " addon so they can be installed in game.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		auras, err := wadman.FindAuras(install)
+		if err != nil {
+			bail("Unable to read SavedVariables: %v", err)
+		}
+
+		updates, err := wadman.CheckAuras(ctx, auras)
+		if err != nil {
+			bail("Unable to check Wago.io for updates: %v", err)
+		}
+
+		if !dryRun {
+			if err := wadman.WriteCompanion(install, updates); err != nil {
+				bail("Unable to write %s addon: %v", wadman.CompanionAddon, err)
+			}
+		}
+
+		records := []auraRecord{}
+		for _, aura := range auras {
+			record := auraRecord{
+				Type:             aura.Kind,
+				Slug:             aura.Slug,
+				Name:             aura.Name,
+				InstalledVersion: aura.Version,
+				Ignored:          aura.Ignored,
+			}
+
+			for _, u := range updates {
+				if u.Aura == aura {
+					record.LatestVersion = u.Import.Version
+					record.LatestSemver = u.Import.VersionString
+					record.Outdated = true
+					if showChangelogs {
+						record.Changelog = wadman.ChangelogText(u.Import.Changelog.Text)
+					}
+				}
+			}
+
+			records = append(records, record)
+		}
+
+		if structuredOutput() {
+			writeOutput(records)
+			return
+		}
+
+		if len(records) == 0 {
+			fmt.Println("No WeakAuras or Plater imports from Wago.io were found")
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Type", "Slug", "Name", "Installed", "Latest"})
+		table.SetAutoWrapText(false)
+		for _, record := range records {
+			latest := ""
+			if record.Outdated {
+				latest = strconv.FormatInt(record.LatestVersion, 10)
+				if record.LatestSemver != "" {
+					latest = fmt.Sprintf("%d (%s)", record.LatestVersion, record.LatestSemver)
+				}
+			} else if record.Ignored {
+				latest = "ignored"
+			}
+			table.Append([]string{record.Type, record.Slug, record.Name, strconv.FormatInt(record.InstalledVersion, 10), latest})
+		}
+		table.Render()
+
+		if showChangelogs {
+			for _, record := range records {
+				if record.Outdated {
+					printChangelog(os.Stdout, record.Name, record.LatestSemver, record.Changelog)
+				}
+			}
+		}
+
+		if dryRun {
+			fmt.Printf("%d updates available\n", len(updates))
+		} else {
+			fmt.Printf("Wrote %d updates to %s. Reload your UI in game to see them.\n", len(updates), wadman.CompanionAddon)
+		}
+	},
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"io"
+	"strings"
+)
+
+// fetchChangelog retrieves the changelog for the given release of an addon. Changelogs are purely informational, so
+// failures are logged and an empty string returned rather than treating them as errors.
+func fetchChangelog(addon wadman.Addon, release *wadman.Release) string {
+	changelog, err := wadman.Changelog(ctx, addon, release)
+	if err != nil {
+		logf("Unable to retrieve changelog for addon '%s': %v\n", addon.DisplayName(), err)
+		return ""
+	}
+	return changelog
+}
+
+// printChangelog writes a changelog to the given writer, indented underneath a heading naming the addon and version.
+func printChangelog(w io.Writer, name string, version string, changelog string) {
+	_, _ = fmt.Fprintf(w, "\nChanges in %s %s:\n\n", name, version)
+	if changelog == "" {
+		_, _ = fmt.Fprintf(w, "    No changelog available\n")
+		return
+	}
+
+	for _, line := range strings.Split(changelog, "\n") {
+		if line == "" {
+			_, _ = fmt.Fprintln(w)
+		} else {
+			_, _ = fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman/wow"
+	"github.com/csmith/wadman/wow/toc"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	rootCommand.AddCommand(checkCompatCommand)
+	checkCompatCommand.Flags().StringVarP(&compatInterface, "interface", "i", "", "Check against the given client or interface version (e.g. 10.0.5 or 100005) instead of the installed client")
+	checkCompatCommand.Flags().BoolVarP(&compatAll, "all", "a", false, "Show all addons, not just those that aren't current")
+}
+
+var (
+	compatInterface string
+	compatAll       bool
+)
+
+type compatRecord struct {
+	Directory     string            `json:"directory" yaml:"directory"`
+	Id            string            `json:"id,omitempty" yaml:"id,omitempty"`
+	Managed       bool              `json:"managed" yaml:"managed"`
+	Interface     []int             `json:"interface" yaml:"interface"`
+	Compatibility toc.Compatibility `json:"compatibility" yaml:"compatibility"`
+	Error         string            `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// compatibilityRank orders compatibility states from best to worst.
+var compatibilityRank = map[toc.Compatibility]int{
+	toc.CompatibilityCurrent:  0,
+	toc.CompatibilityUnknown:  1,
+	toc.CompatibilityNewer:    2,
+	toc.CompatibilityOutdated: 3,
+}
+
+var checkCompatCommand = &cobra.Command{
+	Use:   "check-compat",
+	Short: "Check whether installed addons support the current (or an upcoming) game client",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var target int
+		var err error
+		if compatInterface != "" {
+			target, err = wow.InterfaceVersion(compatInterface)
+		} else {
+			target, err = install.ClientInterface()
+		}
+		if err != nil {
+			bail("Unable to determine client interface version: %v", err)
+		}
+
+		records, err := compatRecords(target)
+		if err != nil {
+			bail("Unable to read addon directory: %v", err)
+		}
+
+		if structuredOutput() {
+			writeOutput(records)
+			return
+		}
+
+		counts := make(map[toc.Compatibility]int)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Addon", "Managed by", "Interface", "Status"})
+		table.SetAutoWrapText(false)
+		shown := 0
+		for _, record := range records {
+			counts[record.Compatibility]++
+			if record.Compatibility == toc.CompatibilityCurrent && !compatAll {
+				continue
+			}
+
+			status := string(record.Compatibility)
+			if record.Error != "" {
+				status = fmt.Sprintf("error: %s", record.Error)
+			}
+			table.Append([]string{record.Directory, record.Id, toc.FormatInterface(record.Interface), status})
+			shown++
+		}
+
+		fmt.Printf("Checking %d addons against interface version %d\n\n", len(records), target)
+		if shown > 0 {
+			table.Render()
+			fmt.Println()
+		}
+		fmt.Printf("%d current, %d out of date, %d newer, %d unknown\n",
+			counts[toc.CompatibilityCurrent],
+			counts[toc.CompatibilityOutdated],
+			counts[toc.CompatibilityNewer],
+			counts[toc.CompatibilityUnknown])
+	},
+}
+
+// compatRecords checks the TOC file of every directory in the addons folder against the given interface version.
+// Records are sorted with the least compatible first.
+func compatRecords(target int) ([]compatRecord, error) {
+	addons, err := install.ListAddons()
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]string)
+	for _, addon := range config.Addons {
+		for _, d := range addon.Dirs() {
+			owners[d] = addon.ShortName()
+		}
+	}
+
+	records := []compatRecord{}
+	for _, d := range addons {
+		record := compatRecord{Directory: d, Id: owners[d], Managed: owners[d] != "", Interface: []int{}}
+		if t, err := toc.ForAddon(install, d); err != nil {
+			record.Compatibility = toc.CompatibilityUnknown
+			record.Error = err.Error()
+		} else {
+			record.Interface = append(record.Interface, t.Interface...)
+			record.Compatibility = t.Compatibility(target)
+		}
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		ri, rj := compatibilityRank[records[i].Compatibility], compatibilityRank[records[j].Compatibility]
+		if ri != rj {
+			return ri > rj
+		}
+		return strings.ToLower(records[i].Directory) < strings.ToLower(records[j].Directory)
+	})
+	return records, nil
+}
+
+// worstCompatibility returns the least compatible state of the given directories, based on the records produced by
+// compatRecords.
+func worstCompatibility(records []compatRecord, dirs []string) toc.Compatibility {
+	included := make(map[string]bool)
+	for _, d := range dirs {
+		included[d] = true
+	}
+
+	worst := toc.CompatibilityCurrent
+	for _, record := range records {
+		if included[record.Directory] && compatibilityRank[record.Compatibility] > compatibilityRank[worst] {
+			worst = record.Compatibility
+		}
+	}
+	return worst
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	rootCommand.AddCommand(configCommand)
+}
+
+// configSetting describes a config option that can be viewed and changed with the config command.
+type configSetting struct {
+	get func() string
+	set func(value string) error
+}
+
+var configSettings = map[string]configSetting{
+	"install-path": {
+		get: func() string { return config.InstallPath },
+		set: func(value string) error {
+			config.InstallPath = value
+			return nil
+		},
+	},
+	"wago-api-key": {
+		get: func() string { return config.WagoApiKey },
+		set: func(value string) error {
+			config.WagoApiKey = value
+			return nil
+		},
+	},
+	"wago-io-api-key": {
+		get: func() string { return config.WagoIoApiKey },
+		set: func(value string) error {
+			config.WagoIoApiKey = value
+			return nil
+		},
+	},
+	"api-token": {
+		get: func() string { return config.ApiToken },
+		set: func(value string) error {
+			config.ApiToken = value
+			return nil
+		},
+	},
+	"update-interval": {
+		get: func() string { return daemonInterval().String() },
+		set: func(value string) error {
+			return setDuration(&config.UpdateInterval, value)
+		},
+	},
+	"update-jitter": {
+		get: func() string { return daemonJitter().String() },
+		set: func(value string) error {
+			return setDuration(&config.UpdateJitter, value)
+		},
+	},
+}
+
+var configCommand = &cobra.Command{
+	Use:   "config <setting> [value]",
+	Short: "Show or change a config setting",
+	Long:  fmt.Sprintf("Show or change a config setting. Available settings: %s", strings.Join(configSettingNames(), ", ")),
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		setting, ok := configSettings[args[0]]
+		if !ok {
+			bail("Unknown setting '%s'. Available settings: %s", args[0], strings.Join(configSettingNames(), ", "))
+		}
+
+		if len(args) == 1 {
+			fmt.Println(setting.get())
+			return
+		}
+
+		if err := setting.set(args[1]); err != nil {
+			bail("Invalid value for %s: %v", args[0], err)
+		}
+		saveConfig()
+		logf("Updated %s\n", args[0])
+	},
+}
+
+func configSettingNames() []string {
+	var names []string
+	for name := range configSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setDuration parses a duration such as "6h" or "30m" into the target.
+func setDuration(target *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("duration can't be negative")
+	}
+	*target = d
+	return nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"github.com/csmith/wadman"
+	"github.com/csmith/wadman/wow"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func init() {
+	rootCommand.AddCommand(daemonCommand)
+	daemonCommand.Flags().DurationVar(&daemonIntervalFlag, "interval", 0, "How often to check for updates (default from config, or 6h)")
+	daemonCommand.Flags().DurationVar(&daemonJitterFlag, "jitter", 0, "Maximum random variation in the interval (default from config, or 30m)")
+	daemonCommand.Flags().StringVar(&daemonLogFile, "log-file", "", "File to write the log to, or '-' for stdout (default daemon.log in wadman's cache directory)")
+}
+
+const (
+	defaultUpdateInterval = 6 * time.Hour
+	defaultUpdateJitter   = 30 * time.Minute
+	// minimumUpdateInterval stops the daemon hammering addon sites if it is misconfigured.
+	minimumUpdateInterval = 5 * time.Minute
+	// clientPollInterval is how often the daemon checks whether the game has exited when updates are waiting.
+	clientPollInterval = 30 * time.Second
+)
+
+var (
+	daemonIntervalFlag time.Duration
+	daemonJitterFlag   time.Duration
+	daemonJitterSet    bool
+	daemonLogFile      string
+)
+
+// pendingUpdate is a newer release of an addon that the daemon has found but not yet installed.
+type pendingUpdate struct {
+	addon   wadman.Addon
+	release *wadman.Release
+	// fileId is the file that was installed when the update was found.
+	fileId string
+}
+
+var daemonCommand = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run in the background, updating addons on a schedule",
+	Long: "Run in the background, checking for addon updates on a schedule. Updates are only installed while the " +
+		"game isn't running; if it is, they are installed as soon as it exits.",
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{lockingAnnotation: "per-check"},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Zero is a valid jitter, so the flag only overrides the config if it was given explicitly
+		daemonJitterSet = cmd.Flags().Changed("jitter")
+
+		out, err := openDaemonLog()
+		if err != nil {
+			bail("Unable to open log file: %v", err)
+		}
+		defer out.Close()
+
+		logger := log.New(out, "", log.LstdFlags)
+		logger.Printf("Started: checking for updates every %s (± %s)", daemonInterval(), daemonJitter())
+
+		random := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for {
+			daemonUpdate(logger)
+			if ctx.Err() != nil {
+				break
+			}
+
+			wait := daemonInterval()
+			if jitter := daemonJitter(); jitter > 0 {
+				wait += time.Duration(random.Int63n(int64(2*jitter))) - jitter
+			}
+			if wait < minimumUpdateInterval {
+				wait = minimumUpdateInterval
+			}
+			logger.Printf("Next check at %s", time.Now().Add(wait).Format("2006-01-02 15:04:05"))
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		logger.Printf("Stopped")
+	},
+}
+
+// daemonInterval returns the time between update checks, from the command line flag or the config.
+func daemonInterval() time.Duration {
+	switch {
+	case daemonIntervalFlag > 0:
+		return daemonIntervalFlag
+	case config.UpdateInterval > 0:
+		return config.UpdateInterval
+	default:
+		return defaultUpdateInterval
+	}
+}
+
+// daemonJitter returns the maximum random variation in the interval, from the command line flag or the config.
+func daemonJitter() time.Duration {
+	switch {
+	case daemonJitterSet:
+		return daemonJitterFlag
+	case config.UpdateJitter > 0:
+		return config.UpdateJitter
+	default:
+		return defaultUpdateJitter
+	}
+}
+
+// openDaemonLog opens the log file for appending, creating it if necessary.
+func openDaemonLog() (io.WriteCloser, error) {
+	path := daemonLogFile
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	if path == "" {
+		cache, err := wadman.CachePath()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(cache, "daemon.log")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.FileMode(0644))
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// daemonUpdate reloads the config, checks every addon for updates, and installs any that are found once the game
+// isn't running.
+func daemonUpdate(logger *log.Logger) {
+	// The config may have been changed by other wadman commands since the daemon started
+	if err := acquireLocks(); err != nil {
+		logger.Print(err)
+		return
+	}
+	defer releaseLocks()
+
+	// Webhooks are notified even if nothing was updated, so that earlier notifications can be retried
+	var records []updateRecord
+	defer func() {
+		notifyWebhooks(records, logger.Printf)
+	}()
+
+	var pending []pendingUpdate
+	for _, addon := range config.Addons {
+		if ctx.Err() != nil {
+			return
+		}
+		if addon.Pin() != "" {
+			continue
+		}
+
+		release, newer, err := wadman.CheckForUpdate(ctx, addon, install, nil)
+		if err != nil {
+			logger.Printf("Unable to check for updates to %s: %v", addon.DisplayName(), err)
+			records = append(records, updateRecord{Id: addon.ShortName(), Name: addon.DisplayName(), OldVersion: addon.CurrentVersion(), Error: err.Error()})
+		} else if newer {
+			logger.Printf("Found update for %s: %s -> %s", addon.DisplayName(), addon.CurrentVersion(), release.Version)
+			pending = append(pending, pendingUpdate{addon: addon, release: release, fileId: addon.Locked().FileId})
+		}
+	}
+
+	if len(pending) == 0 {
+		logger.Printf("Checked %d addons: all up to date", len(config.Addons))
+		return
+	}
+
+	proceed, waited := waitForClientExit(logger, len(pending))
+	if !proceed {
+		return
+	} else if waited {
+		if err := acquireLocks(); err != nil {
+			logger.Print(err)
+			return
+		}
+		pending = stillPending(logger, pending)
+	}
+
+	updated := 0
+	for _, p := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+
+		record := updateRecord{Id: p.addon.ShortName(), Name: p.addon.DisplayName(), OldVersion: p.addon.CurrentVersion()}
+		err := wadman.InstallRelease(ctx, p.addon, install, p.release, nil)
+		record.NewVersion = p.addon.CurrentVersion()
+		record.Updated = err == nil
+		record.Error = errorString(err)
+		records = append(records, record)
+
+		if err != nil {
+			logger.Printf("Unable to update %s: %v", p.addon.DisplayName(), err)
+			continue
+		}
+		logger.Printf("Updated %s from %s to %s", p.addon.DisplayName(), record.OldVersion, record.NewVersion)
+		updated++
+	}
+
+	if updated > 0 {
+		if err := writeConfig(); err != nil {
+			logger.Print(err)
+		}
+	}
+}
+
+// waitForClientExit blocks until the game isn't running, returning false if the daemon is stopped first. If the
+// process list can't be read, updates are installed anyway rather than being held back forever. If it has to wait,
+// the locks are released so other copies of wadman can run in the meantime, and waited is true.
+func waitForClientExit(logger *log.Logger, updates int) (proceed bool, waited bool) {
+	for {
+		running, err := wow.ClientRunning()
+		if err != nil {
+			logger.Printf("Unable to check if the game is running: %v", err)
+			return true, waited
+		} else if !running {
+			if waited {
+				logger.Printf("The game has exited")
+			}
+			return true, waited
+		}
+
+		if !waited {
+			logger.Printf("The game is running: waiting for it to exit before installing %d updates", updates)
+			releaseLocks()
+			waited = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, waited
+		case <-time.After(clientPollInterval):
+		}
+	}
+}
+
+// stillPending matches the pending updates to the addons in the reloaded config, dropping any that were removed,
+// pinned or updated by another copy of wadman while the daemon was waiting.
+func stillPending(logger *log.Logger, pending []pendingUpdate) []pendingUpdate {
+	var res []pendingUpdate
+	for _, p := range pending {
+		addon := findAddon(p.addon.ShortName())
+		if addon == nil || addon.Pin() != "" || addon.Locked().FileId != p.fileId {
+			logger.Printf("Skipping update for %s, as it was changed while waiting for the game to exit", p.addon.DisplayName())
+			continue
+		}
+		res = append(res, pendingUpdate{addon: addon, release: p.release, fileId: p.fileId})
+	}
+	return res
+}
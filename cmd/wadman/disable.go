@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCommand.AddCommand(disableCommand)
+	disableCommand.Flags().StringVarP(&disableCharacter, "character", "c", "", "Disable the addons for a single character (realm/name)")
+	disableCommand.Flags().BoolVarP(&disableAll, "all", "a", false, "Disable the addons for all characters")
+}
+
+var disableCharacter string
+var disableAll bool
+
+var disableCommand = &cobra.Command{
+	Use:   "disable <id [id [id [...]]]> <--character realm/name | --all>",
+	Short: "Disable addons in the WoW client",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setAddonsEnabled(args, disableCharacter, disableAll, false)
+	},
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman/wow"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCommand.AddCommand(enableCommand)
+	enableCommand.Flags().StringVarP(&enableCharacter, "character", "c", "", "Enable the addons for a single character (realm/name)")
+	enableCommand.Flags().BoolVarP(&enableAll, "all", "a", false, "Enable the addons for all characters")
+}
+
+var enableCharacter string
+var enableAll bool
+
+var enableCommand = &cobra.Command{
+	Use:   "enable <id [id [id [...]]]> <--character realm/name | --all>",
+	Short: "Enable addons in the WoW client",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setAddonsEnabled(args, enableCharacter, enableAll, true)
+	},
+}
+
+// setAddonsEnabled updates the AddOns.txt file for the selected characters to enable or disable all of the directories
+// belonging to the given managed addons.
+func setAddonsEnabled(args []string, character string, all bool, enabled bool) {
+	if (character == "") == !all {
+		bail("Please specify either --character realm/name or --all")
+	}
+
+	characters, err := selectCharacters(character)
+	if err != nil {
+		bail("Unable to list characters: %v", err)
+	}
+
+	included := toIdMap(args)
+
+	var dirs []string
+	var names []string
+	matched := make(map[string]bool)
+	for i := range config.Addons {
+		addon := config.Addons[i]
+		if included[addon.ShortName()] {
+			dirs = append(dirs, addon.Dirs()...)
+			names = append(names, addon.DisplayName())
+			matched[addon.ShortName()] = true
+		}
+	}
+
+	for _, id := range args {
+		if !matched[normaliseId(id)] {
+			fmt.Printf("No addon found matching '%s'\n", id)
+			addonFailed()
+		}
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	state := "Disabled"
+	if enabled {
+		state = "Enabled"
+	}
+
+	for i := range characters {
+		if err := characters[i].SetAddonsEnabled(dirs, enabled); err != nil {
+			fmt.Printf("Unable to update addons for %s: %v\n", characters[i], err)
+			addonFailed()
+		} else {
+			fmt.Printf("%s %d addon(s) for %s\n", state, len(names), characters[i])
+		}
+	}
+}
+
+// selectCharacters returns the characters matching the given realm/name, or all characters if it is empty.
+func selectCharacters(character string) ([]*wow.Character, error) {
+	characters, err := install.Characters()
+	if err != nil {
+		return nil, err
+	}
+
+	if character == "" {
+		if len(characters) == 0 {
+			return nil, fmt.Errorf("no characters found")
+		}
+		return characters, nil
+	}
+
+	var selected []*wow.Character
+	for i := range characters {
+		if characters[i].Matches(character) {
+			selected = append(selected, characters[i])
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no character found matching '%s'", character)
+	}
+	return selected, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+func init() {
+	rootCommand.AddCommand(exportCommand)
+}
+
+var exportCommand = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export a portable list of installed addons",
+	Long: "Writes a list of installed addons, along with their channels and pins, to the given file or stdout. " +
+		"Addons installed from local files or directories are left out.",
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var out io.WriteCloser = os.Stdout
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Create(args[0])
+			if err != nil {
+				bail("Unable to create export file: %v", err)
+			}
+			out = f
+		}
+
+		list, skipped := wadman.NewAddonList(config.Addons)
+		for _, addon := range skipped {
+			// The list may be written to stdout, so warnings always go to stderr
+			fmt.Fprintf(os.Stderr, "Warning: not exporting '%s' as it was installed from a local path\n", addon.DisplayName())
+		}
+
+		if err := list.Write(out); err != nil {
+			bail("Unable to write addon list: %v", err)
+		}
+
+		if err := out.Close(); err != nil {
+			bail("Unable to write addon list: %v", err)
+		}
+	},
+}
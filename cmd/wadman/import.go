@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func init() {
+	rootCommand.AddCommand(importCommand)
+}
+
+var importCommand = &cobra.Command{
+	Use:   "import <file|url|->",
+	Short: "Install all addons in an exported addon list",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := readAddonList(args[0])
+		if err != nil {
+			bail("Unable to read addon list from %s: %v", args[0], err)
+		}
+
+		defer saveConfig()
+
+		for _, entry := range list.Addons {
+			if interrupted() {
+				break
+			}
+
+			addon, err := wadman.NewAddon(entry.Id)
+			if err != nil {
+				fmt.Printf("%s: %v\n", entry.Id, err)
+				addonFailed()
+				continue
+			}
+
+			addon.SetChannel(entry.Channel)
+			addPinnedAddon(entry.Id, addon, entry.Pin)
+		}
+	},
+}
+
+// readAddonList reads an addon list from stdin ("-"), a HTTP(S) URL, or a local file.
+func readAddonList(source string) (*wadman.AddonList, error) {
+	var in io.ReadCloser
+	if source == "-" {
+		in = ioutil.NopCloser(os.Stdin)
+	} else if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusOK {
+			_ = res.Body.Close()
+			return nil, fmt.Errorf("unexpected response: %s", res.Status)
+		}
+		in = res.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		in = f
+	}
+
+	defer in.Close()
+	return wadman.ReadAddonList(in)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/csmith/wadman/wow"
+	"github.com/csmith/wadman/wow/toc"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	rootCommand.AddCommand(infoCommand)
+	infoCommand.Flags().BoolVar(&infoFiles, "files", false, "List every downloadable file instead of the latest per channel and flavour")
+}
+
+var infoFiles bool
+
+type infoRecord struct {
+	Id          string          `json:"id" yaml:"id"`
+	Name        string          `json:"name" yaml:"name"`
+	Managed     bool            `json:"managed" yaml:"managed"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Authors     []string        `json:"authors" yaml:"authors"`
+	Website     string          `json:"website,omitempty" yaml:"website,omitempty"`
+	Version     string          `json:"version,omitempty" yaml:"version,omitempty"`
+	Channel     wadman.Channel  `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Pin         string          `json:"pin,omitempty" yaml:"pin,omitempty"`
+	Latest      string          `json:"latest_version,omitempty" yaml:"latest_version,omitempty"`
+	Changelog   string          `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+	Directories []infoDirectory `json:"directories" yaml:"directories"`
+	Files       []infoFile      `json:"files" yaml:"files"`
+	Error       string          `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type infoDirectory struct {
+	Name                 string   `json:"name" yaml:"name"`
+	Interface            string   `json:"interface,omitempty" yaml:"interface,omitempty"`
+	Version              string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Dependencies         []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	OptionalDependencies []string `json:"optional_dependencies,omitempty" yaml:"optional_dependencies,omitempty"`
+	DisabledFor          []string `json:"disabled_for,omitempty" yaml:"disabled_for,omitempty"`
+	Error                string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type infoFile struct {
+	Id           string         `json:"id" yaml:"id"`
+	Version      string         `json:"version" yaml:"version"`
+	Channel      wadman.Channel `json:"channel" yaml:"channel"`
+	Flavour      wow.Flavour    `json:"flavour" yaml:"flavour"`
+	Date         *time.Time     `json:"date,omitempty" yaml:"date,omitempty"`
+	GameVersions []string       `json:"game_versions" yaml:"game_versions"`
+}
+
+var infoCommand = &cobra.Command{
+	Use:   "info <id>",
+	Short: "Show details of an addon",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := normaliseId(args[0])
+
+		addon := findAddon(id)
+		record := infoRecord{Id: id, Managed: addon != nil, Authors: []string{}, Directories: []infoDirectory{}, Files: []infoFile{}}
+		if addon == nil {
+			var err error
+			if addon, err = wadman.NewAddon(id); err != nil {
+				bail("%s: %v", args[0], err)
+			}
+		}
+
+		record.Name = addon.DisplayName()
+		if record.Managed {
+			record.Version = addon.CurrentVersion()
+			record.Channel = addon.Channel()
+			record.Pin = addon.Pin()
+		}
+
+		if details, err := addonDetails(addon); err != nil {
+			record.Error = err.Error()
+			addonFailed()
+		} else {
+			record.Name = details.Name
+			record.Description = details.Description
+			record.Authors = append(record.Authors, details.Authors...)
+			record.Website = details.Website
+			record.Files = fileRecords(details.Files, !infoFiles)
+
+			if latest, _, err := wadman.CheckForUpdate(ctx, addon, install, nil); err == nil {
+				record.Latest = latest.Version
+				record.Changelog = fetchChangelog(addon, latest)
+			}
+		}
+
+		dirs := addon.Dirs()
+		if !record.Managed {
+			dirs = unmanagedDirs(id)
+		}
+		record.Directories = directoryInfo(dirs)
+
+		if structuredOutput() {
+			writeOutput(record)
+			return
+		}
+
+		printInfo(record)
+	},
+}
+
+// normaliseId converts a user-supplied ID into the canonical form used by the addon's short name, e.g. making local
+// paths absolute. Bare numbers are assumed to be IDs for the default source.
+func normaliseId(id string) string {
+	if i, err := strconv.Atoi(id); err == nil {
+		return fmt.Sprintf("%s:%d", wadman.DefaultType, i)
+	}
+	if addon, err := wadman.NewAddon(id); err == nil {
+		return addon.ShortName()
+	}
+	return strings.ToLower(id)
+}
+
+// addonDetails retrieves the details of the addon from its provider.
+func addonDetails(addon wadman.Addon) (*wadman.AddonDetails, error) {
+	provider, err := wadman.ProviderFor(addon.Type())
+	if err != nil {
+		return nil, err
+	}
+	return provider.Details(ctx, addon)
+}
+
+// fileRecords converts the given files into records. If latestOnly is true, only the most recent file for each
+// combination of channel and flavour is included.
+func fileRecords(files []wadman.File, latestOnly bool) []infoFile {
+	files = append([]wadman.File{}, files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Date.After(files[j].Date)
+	})
+
+	seen := make(map[string]bool)
+	res := []infoFile{}
+	for _, f := range files {
+		key := fmt.Sprintf("%s/%s", f.Channel, f.Flavour)
+		if latestOnly && seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		record := infoFile{
+			Id:           f.FileId,
+			Version:      f.Version,
+			Channel:      f.Channel,
+			Flavour:      f.Flavour,
+			GameVersions: append([]string{}, f.GameVersions...),
+		}
+		if !f.Date.IsZero() {
+			date := f.Date
+			record.Date = &date
+		}
+		res = append(res, record)
+	}
+	return res
+}
+
+// unmanagedDirs finds directories in the addons folder whose TOC files specify the given ID.
+func unmanagedDirs(id string) []string {
+	addons, err := install.ListAddons()
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, a := range addons {
+		t, err := toc.ForAddon(install, a)
+		if err != nil {
+			continue
+		}
+
+		for _, tocId := range tocIds(t) {
+			if tocId == id {
+				dirs = append(dirs, a)
+			}
+		}
+	}
+	return dirs
+}
+
+// directoryInfo reads the TOC meta-data and disabled status of each of the given addon directories.
+func directoryInfo(dirs []string) []infoDirectory {
+	characters, _ := install.Characters()
+	disabled := make(map[*wow.Character]map[string]bool)
+	for _, c := range characters {
+		disabled[c], _ = c.DisabledAddons()
+	}
+
+	res := []infoDirectory{}
+	for _, d := range dirs {
+		record := infoDirectory{Name: d}
+		if t, err := toc.ForAddon(install, d); err != nil {
+			record.Error = err.Error()
+		} else {
+			record.Interface = t.InterfaceString()
+			record.Version = t.Version
+			record.Dependencies = append(record.Dependencies, t.Dependencies...)
+			record.OptionalDependencies = append(record.OptionalDependencies, t.OptionalDeps...)
+			sort.Strings(record.Dependencies)
+			sort.Strings(record.OptionalDependencies)
+		}
+
+		for _, c := range characters {
+			if disabled[c][d] {
+				record.DisabledFor = append(record.DisabledFor, c.String())
+			}
+		}
+		res = append(res, record)
+	}
+	return res
+}
+
+func printInfo(record infoRecord) {
+	fmt.Printf("%s (%s)\n", record.Name, record.Id)
+	if len(record.Authors) > 0 {
+		fmt.Printf("By %s\n", strings.Join(record.Authors, ", "))
+	}
+	if record.Website != "" {
+		fmt.Printf("%s\n", record.Website)
+	}
+	if record.Description != "" {
+		fmt.Printf("\n%s\n", record.Description)
+	}
+	if record.Error != "" {
+		fmt.Printf("\nUnable to retrieve addon details: %s\n", record.Error)
+	}
+
+	fmt.Println()
+	if record.Managed {
+		fmt.Printf("Installed version: %s\n", record.Version)
+		if record.Channel != wadman.ChannelDefault {
+			fmt.Printf("Channel: %s\n", record.Channel)
+		}
+		if record.Pin != "" {
+			fmt.Printf("Pinned to file: %s\n", record.Pin)
+		}
+	} else if len(record.Directories) > 0 {
+		fmt.Printf("Installed, but not managed by wadman\n")
+	} else {
+		fmt.Printf("Not installed\n")
+	}
+
+	if len(record.Directories) > 0 {
+		fmt.Printf("\nDirectories:\n\n")
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Directory", "Interface", "Version", "Dependencies", "Disabled for"})
+		table.SetAutoWrapText(false)
+		for _, d := range record.Directories {
+			if d.Error != "" {
+				table.Append([]string{d.Name, "", "", fmt.Sprintf("error: %s", d.Error), ""})
+				continue
+			}
+
+			deps := d.Dependencies
+			for _, o := range d.OptionalDependencies {
+				deps = append(deps, fmt.Sprintf("%s (optional)", o))
+			}
+			table.Append([]string{d.Name, d.Interface, d.Version, strings.Join(deps, ", "), strings.Join(d.DisabledFor, ", ")})
+		}
+		table.Render()
+	}
+
+	if len(record.Files) > 0 {
+		if infoFiles {
+			fmt.Printf("\nAvailable files:\n\n")
+		} else {
+			fmt.Printf("\nLatest files (use --files to see all):\n\n")
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"File ID", "Version", "Channel", "Flavour", "Date", "Game versions"})
+		table.SetAutoWrapText(false)
+		for _, f := range record.Files {
+			var date string
+			if f.Date != nil {
+				date = f.Date.Format("2006-01-02 15:04")
+			}
+			table.Append([]string{f.Id, f.Version, string(f.Channel), string(f.Flavour), date, strings.Join(f.GameVersions, ", ")})
+		}
+		table.Render()
+	}
+
+	if record.Latest != "" {
+		printChangelog(os.Stdout, record.Name, record.Latest, record.Changelog)
+	}
+}
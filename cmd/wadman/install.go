@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func init() {
+	rootCommand.AddCommand(installCommand)
+	installCommand.Flags().BoolVar(&frozen, "frozen", false, "Install exactly the versions recorded in the lockfile")
+	installCommand.Flags().StringVar(&lockfilePath, "lockfile", "", "Read the lockfile from the given path instead of the default location")
+}
+
+var frozen bool
+var lockfilePath string
+
+var installCommand = &cobra.Command{
+	Use:   "install <id@file|id@version|id@latest [...]> | --frozen",
+	Short: "Install specific addon versions, or the exact versions recorded in a lockfile",
+	Args:  cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if frozen && len(args) > 0 {
+			bail("Addons can't be specified when installing from the lockfile")
+		} else if frozen {
+			installFrozen()
+		} else if len(args) > 0 {
+			installVersions(args)
+		} else {
+			bail("Please specify addons to install (e.g. curse:3358@1234), or --frozen to install from the lockfile")
+		}
+	},
+}
+
+// installVersions installs the specific files requested in each of the arguments, and pins the addons to them.
+func installVersions(args []string) {
+	defer saveConfig()
+
+	for _, arg := range args {
+		if interrupted() {
+			break
+		}
+
+		// Use the last @ sign, as some IDs (such as git URLs) can contain them
+		i := strings.LastIndex(arg, "@")
+		if i == -1 || i == len(arg)-1 {
+			fmt.Printf("%s: Please specify a file ID or version, e.g. %s@1234\n", arg, arg)
+			addonFailed()
+			continue
+		}
+
+		id, spec := normaliseId(arg[:i]), arg[i+1:]
+		addon := findAddon(id)
+		existing := addon != nil
+		if !existing {
+			var err error
+			if addon, err = wadman.NewAddon(id); err != nil {
+				fmt.Printf("%s: %v\n", arg, err)
+				addonFailed()
+				continue
+			}
+		}
+
+		if strings.EqualFold(spec, "latest") {
+			installLatest(arg, addon, existing)
+			continue
+		}
+
+		release, err := wadman.FindRelease(ctx, addon, spec)
+		if err != nil {
+			fmt.Printf("%s: %v\n", arg, err)
+			addonFailed()
+			continue
+		}
+
+		if err := wadman.InstallRelease(ctx, addon, install, release, nil); err != nil {
+			fmt.Printf("Unable to install addon %s: %v\n", arg, err)
+			addonFailed()
+			continue
+		}
+
+		addon.SetPin(release.FileId)
+		fmt.Printf("Installed addon '%s' version %s (pinned to file %s)\n", addon.DisplayName(), addon.CurrentVersion(), release.FileId)
+		if !existing {
+			config.Addons = append(config.Addons, addon)
+		}
+	}
+}
+
+// installLatest removes any pin from the addon and updates it to the latest file, so that it is kept up-to-date again.
+func installLatest(arg string, addon wadman.Addon, existing bool) {
+	pinned := addon.Pin() != ""
+	addon.SetPin("")
+
+	updated, err := wadman.Update(ctx, addon, install, nil, false)
+	if err != nil {
+		fmt.Printf("Unable to install addon %s: %v\n", arg, err)
+		addonFailed()
+		return
+	}
+
+	if !existing {
+		config.Addons = append(config.Addons, addon)
+	}
+
+	if updated {
+		fmt.Printf("Installed addon '%s' version %s\n", addon.DisplayName(), addon.CurrentVersion())
+	} else if pinned {
+		fmt.Printf("Unpinned addon '%s', which is already the latest version (%s)\n", addon.DisplayName(), addon.CurrentVersion())
+	} else {
+		fmt.Printf("Addon '%s' is already the latest version (%s)\n", addon.DisplayName(), addon.CurrentVersion())
+	}
+}
+
+// installFrozen installs exactly the addons and versions recorded in the lockfile, removing any others. The lockfile
+// itself is never modified.
+func installFrozen() {
+	path := lockfilePath
+	if path == "" {
+		var err error
+		if path, err = wadman.LockfilePath(); err != nil {
+			bail("Unable to build lockfile path: %v", err)
+		}
+	}
+
+	lock, err := wadman.LoadLockfile(path)
+	if err != nil {
+		bail("Unable to load lockfile from %s: %v", path, err)
+	}
+
+	// Only the config is saved, so that addons which fail to install aren't dropped from the lockfile
+	defer saveConfigOnly()
+
+	// Remove any addons that aren't in the lockfile
+	var addons []wadman.Addon
+	for i := range config.Addons {
+		addon := config.Addons[i]
+		if lock.Entry(addon.ShortName()) != nil || !removeAddonDirs(addon) {
+			addons = append(addons, addon)
+		}
+	}
+	config.Addons = addons
+
+	for _, entry := range lock.Addons {
+		if interrupted() {
+			break
+		}
+
+		addon := findAddon(entry.Id)
+		if addon == nil {
+			if addon, err = wadman.NewAddon(entry.Id); err != nil {
+				fmt.Printf("%s: %v\n", entry.Id, err)
+				addonFailed()
+				continue
+			}
+		} else if addon.Locked() == entry && install.HasAddons(addon.Dirs()) {
+			continue
+		}
+
+		if entry.Checksum == "" {
+			fmt.Printf("Unable to install addon %s: lockfile entry has no checksum (run 'wadman update --force %s' where the lockfile was created to record one)\n", entry.Id, entry.Id)
+			addonFailed()
+			continue
+		}
+
+		if err := addon.Install(ctx, install, entry.Release(), nil); err != nil {
+			fmt.Printf("Unable to install addon %s: %v\n", entry.Id, err)
+			addonFailed()
+			continue
+		}
+
+		fmt.Printf("Installed addon '%s' version %s\n", addon.DisplayName(), addon.CurrentVersion())
+		if findAddon(entry.Id) == nil {
+			config.Addons = append(config.Addons, addon)
+		}
+	}
+}
+
+// findAddon returns the configured addon with the given short name, or nil if there isn't one.
+func findAddon(shortName string) wadman.Addon {
+	for i := range config.Addons {
+		if config.Addons[i].ShortName() == shortName {
+			return config.Addons[i]
+		}
+	}
+	return nil
+}
@@ -2,13 +2,40 @@ package main
 
 import (
 	"fmt"
+	"github.com/csmith/wadman/wow"
+	"github.com/csmith/wadman/wow/toc"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"os"
+	"strings"
+	"time"
 )
 
 func init() {
 	rootCommand.AddCommand(listCommand)
+	listCommand.Flags().StringVarP(&listCharacter, "character", "c", "", "Only show the status of addons for a single character (realm/name)")
+}
+
+var listCharacter string
+
+type listRecord struct {
+	Id                   string     `json:"id" yaml:"id"`
+	Name                 string     `json:"name" yaml:"name"`
+	Version              string     `json:"version" yaml:"version"`
+	LastUpdated          *time.Time `json:"last_updated,omitempty" yaml:"last_updated,omitempty"`
+	Directories          []string   `json:"directories" yaml:"directories"`
+	Disabled             bool       `json:"disabled" yaml:"disabled"`
+	DisabledFor          []string   `json:"disabled_for,omitempty" yaml:"disabled_for,omitempty"`
+	PartiallyDisabledFor []string   `json:"partially_disabled_for,omitempty" yaml:"partially_disabled_for,omitempty"`
+	Compatibility        string     `json:"compatibility,omitempty" yaml:"compatibility,omitempty"`
+	Error                string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// listOutput is the structured output of the list command. Unmanaged only includes directories whose interface
+// version doesn't match the game's.
+type listOutput struct {
+	Addons    []listRecord   `json:"addons" yaml:"addons"`
+	Unmanaged []compatRecord `json:"unmanaged" yaml:"unmanaged"`
 }
 
 var listCommand = &cobra.Command{
@@ -16,43 +43,138 @@ var listCommand = &cobra.Command{
 	Short: "List currently installed addons",
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		disabled, err := install.DisabledAddons()
-		if err != nil {
-			fmt.Printf("Unable to list disabled addons: %v\n", err)
-			disabled = make(map[string]bool)
+		characters, err := selectCharacters(listCharacter)
+		if err != nil && listCharacter != "" {
+			bail("Unable to find character: %v", err)
+		}
+
+		records, errors, compat := listRecords(characters)
+
+		unmanaged := []compatRecord{}
+		for _, c := range compat {
+			if !c.Managed && (c.Compatibility == toc.CompatibilityOutdated || c.Compatibility == toc.CompatibilityNewer) {
+				unmanaged = append(unmanaged, c)
+			}
+		}
+
+		if structuredOutput() {
+			writeOutput(listOutput{Addons: records, Unmanaged: unmanaged})
+			return
 		}
 
-		fmt.Printf("%d addons installed:\n\n", len(config.Addons))
+		for c, err := range errors {
+			if err != nil {
+				fmt.Printf("Unable to list disabled addons for %s: %v\n", c, err)
+			}
+		}
+
+		fmt.Printf("%d addons installed:\n\n", len(records))
 		table := tablewriter.NewWriter(os.Stdout)
 		table.SetHeader([]string{"ID", "Name", "Version", "Last updated", "Status"})
 		table.SetAutoWrapText(false)
-		for i := range config.Addons {
-			addon := config.Addons[i]
+		for _, record := range records {
+			var lastUpdated string
+			if record.LastUpdated != nil {
+				lastUpdated = record.LastUpdated.Format("2006-01-02 15:04")
+			}
+
+			table.Append([]string{record.Id, record.Name, record.Version, lastUpdated, record.status()})
+		}
+		table.Render()
+
+		if len(unmanaged) > 0 {
+			var dirs []string
+			for _, c := range unmanaged {
+				dirs = append(dirs, c.Directory)
+			}
+			fmt.Printf("\nUnmanaged addons that don't match the game's interface version: %s\n", strings.Join(dirs, ", "))
+		}
+	},
+}
+
+// listRecords describes every managed addon, including which of the given characters have disabled it. Errors reading
+// each character's settings are returned separately, as are the compatibility records used to check interface
+// versions (which are nil if the client version isn't known).
+func listRecords(characters []*wow.Character) ([]listRecord, map[*wow.Character]error, []compatRecord) {
+	disabled := make(map[*wow.Character]map[string]bool)
+	errors := make(map[*wow.Character]error)
+	for i := range characters {
+		disabled[characters[i]], errors[characters[i]] = characters[i].DisabledAddons()
+	}
+
+	// Compatibility can only be checked if the client version is known
+	var compat []compatRecord
+	if client, err := install.ClientInterface(); err == nil {
+		compat, _ = compatRecords(client)
+	}
+
+	records := []listRecord{}
+	for i := range config.Addons {
+		addon := config.Addons[i]
+		record := listRecord{
+			Id:          addon.ShortName(),
+			Name:        addon.DisplayName(),
+			Version:     addon.CurrentVersion(),
+			Directories: addon.Dirs(),
+		}
+
+		if !addon.LastUpdated().IsZero() {
+			lastUpdated := addon.LastUpdated()
+			record.LastUpdated = &lastUpdated
+		}
+
+		var errs []string
+		for _, c := range characters {
+			if errors[c] != nil {
+				errs = append(errs, fmt.Sprintf("unable to read addons for %s: %v", c, errors[c]))
+				continue
+			}
+
 			count := 0
-			dirs := addon.Dirs()
-			for d := range dirs {
-				if disabled[dirs[d]] {
+			for _, d := range record.Directories {
+				if disabled[c][d] {
 					count++
 				}
 			}
 
-			var status string
-			if count == len(dirs) {
-				status = "disabled"
+			if count > 0 && count == len(record.Directories) {
+				record.DisabledFor = append(record.DisabledFor, c.String())
 			} else if count > 0 {
-				status = fmt.Sprintf("disabled (%d/%d)", count, len(dirs))
+				record.PartiallyDisabledFor = append(record.PartiallyDisabledFor, c.String())
 			}
+		}
 
-			var lastUpdated string
-			if addon.LastUpdated().IsZero() {
-				lastUpdated = ""
-			} else {
-				lastUpdated = addon.LastUpdated().Format("2006-01-02 15:04")
-			}
+		if compat != nil {
+			record.Compatibility = string(worstCompatibility(compat, record.Directories))
+		}
+
+		record.Disabled = len(characters) > 0 && len(record.DisabledFor) == len(characters)
+		record.Error = strings.Join(errs, "; ")
+		records = append(records, record)
+	}
+	return records, errors, compat
+}
 
+// status describes which characters have disabled all or some of the addon's directories, and whether the game
+// will consider it out of date.
+func (r listRecord) status() string {
+	var parts []string
+	switch toc.Compatibility(r.Compatibility) {
+	case toc.CompatibilityOutdated:
+		parts = append(parts, "out of date")
+	case toc.CompatibilityNewer:
+		parts = append(parts, "newer interface")
+	}
 
-			table.Append([]string{addon.ShortName(), addon.DisplayName(), addon.CurrentVersion(), lastUpdated, status})
-		}
-		table.Render()
-	},
+	if r.Disabled {
+		return strings.Join(append(parts, "disabled"), "; ")
+	}
+
+	if len(r.DisabledFor) > 0 {
+		parts = append(parts, fmt.Sprintf("disabled for %s", strings.Join(r.DisabledFor, ", ")))
+	}
+	if len(r.PartiallyDisabledFor) > 0 {
+		parts = append(parts, fmt.Sprintf("partially disabled for %s", strings.Join(r.PartiallyDisabledFor, ", ")))
+	}
+	return strings.Join(parts, "; ")
 }
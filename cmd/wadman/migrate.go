@@ -1,18 +1,81 @@
 package main
 
 import (
+	"fmt"
+	"github.com/csmith/wadman"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 func init() {
 	rootCommand.AddCommand(migrateCommand)
+	migrateCommand.Flags().BoolVar(&migrateCheck, "check", false, "Report the changes that would be made without saving them")
+}
+
+var migrateCheck bool
+
+type migrationRecord struct {
+	Path       string                   `json:"path" yaml:"path"`
+	Version    int                      `json:"version" yaml:"version"`
+	Target     int                      `json:"target" yaml:"target"`
+	Migrations []wadman.ConfigMigration `json:"migrations" yaml:"migrations"`
 }
 
 var migrateCommand = &cobra.Command{
 	Use:   "migrate",
-	Short: "Forces a config file migration",
+	Short: "Upgrades the config file to the latest version",
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		saveConfig()
+		version, migrations, err := wadman.PendingConfigMigrations(configPath)
+		if os.IsNotExist(err) {
+			bail("No config file found at %s", configPath)
+		} else if err != nil {
+			bail("Unable to check config file %s: %v", configPath, err)
+		}
+
+		if migrateCheck {
+			if structuredOutput() {
+				target := version
+				if len(migrations) > 0 {
+					target = migrations[len(migrations)-1].Version
+				}
+				writeOutput(migrationRecord{Path: configPath, Version: version, Target: target, Migrations: migrations})
+				return
+			}
+
+			if len(migrations) == 0 {
+				fmt.Printf("Config file %s is up to date (version %d)\n", configPath, version)
+				return
+			}
+
+			fmt.Printf("Config file %s is version %d. Migrating it would:\n", configPath, version)
+			printMigrations(migrations)
+			return
+		}
+
+		backup, err := writeConfigFile()
+		if err != nil {
+			bail("%v", err)
+		}
+
+		if len(migrations) == 0 {
+			fmt.Printf("Config file %s was already up to date (version %d)\n", configPath, version)
+			return
+		}
+
+		fmt.Printf("Migrated config file %s from version %d:\n", configPath, version)
+		printMigrations(migrations)
+		if backup != "" {
+			fmt.Printf("The old config file was saved to %s\n", backup)
+		}
 	},
 }
+
+func printMigrations(migrations []wadman.ConfigMigration) {
+	for _, m := range migrations {
+		fmt.Printf("\tVersion %d: %s\n", m.Version, m.Description)
+		for _, change := range m.Changes {
+			fmt.Printf("\t\t- %s\n", change)
+		}
+	}
+}
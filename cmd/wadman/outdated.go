@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func init() {
+	rootCommand.AddCommand(outdatedCommand)
+	outdatedCommand.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show debug information when checking for updates")
+	outdatedCommand.Flags().BoolVarP(&showChangelogs, "changelog", "c", false, "Show the changelog for each available update")
+}
+
+var showChangelogs bool
+
+type outdatedRecord struct {
+	Id             string `json:"id" yaml:"id"`
+	Name           string `json:"name" yaml:"name"`
+	CurrentVersion string `json:"current_version" yaml:"current_version"`
+	LatestVersion  string `json:"latest_version" yaml:"latest_version"`
+	Outdated       bool   `json:"outdated" yaml:"outdated"`
+	Changelog      string `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+	Error          string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+var outdatedCommand = &cobra.Command{
+	Use:   "outdated [id [id ...]]",
+	Short: "Check installed addons for updates without installing them",
+	Args:  cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		observer := verboseObserver()
+
+		records := []outdatedRecord{}
+		for _, addon := range selectAddons(args) {
+			if interrupted() {
+				break
+			}
+
+			record := checkOutdated(addon, observer, showChangelogs)
+			if record.Error != "" {
+				addonFailed()
+			}
+			records = append(records, record)
+		}
+
+		if structuredOutput() {
+			writeOutput(records)
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"ID", "Name", "Installed", "Latest"})
+		table.SetAutoWrapText(false)
+		count := 0
+		for _, record := range records {
+			if record.Error != "" {
+				table.Append([]string{record.Id, record.Name, record.CurrentVersion, fmt.Sprintf("error: %s", record.Error)})
+			} else if record.Outdated {
+				table.Append([]string{record.Id, record.Name, record.CurrentVersion, record.LatestVersion})
+			} else {
+				continue
+			}
+			count++
+		}
+
+		if count == 0 {
+			fmt.Printf("All %d addons are up to date\n", len(records))
+		} else {
+			table.Render()
+		}
+
+		if showChangelogs {
+			for _, record := range records {
+				if record.Outdated {
+					printChangelog(os.Stdout, record.Name, record.LatestVersion, record.Changelog)
+				}
+			}
+		}
+	},
+}
+
+// checkOutdated checks whether a newer version of the addon is available, optionally including its changelog.
+func checkOutdated(addon wadman.Addon, observer wadman.Observer, changelog bool) outdatedRecord {
+	record := outdatedRecord{
+		Id:             addon.ShortName(),
+		Name:           addon.DisplayName(),
+		CurrentVersion: addon.CurrentVersion(),
+	}
+
+	latest, newer, err := wadman.CheckForUpdate(ctx, addon, install, observer)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+
+	record.LatestVersion = latest.Version
+	record.Outdated = newer
+	if newer && changelog {
+		record.Changelog = fetchChangelog(addon, latest)
+	}
+	return record
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+)
+
+func init() {
+	rootCommand.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "Output format: text, json or yaml")
+}
+
+const (
+	outputText = "text"
+	outputJson = "json"
+	outputYaml = "yaml"
+)
+
+// Exit codes used by wadman. These are considered part of the public interface, and should not be changed.
+const (
+	// exitSuccess indicates the command completed without any errors.
+	exitSuccess = 0
+	// exitFatal indicates the command could not be run at all, e.g. because the config file was invalid.
+	exitFatal = 1
+	// exitAddonErrors indicates the command ran, but one or more addons could not be processed.
+	exitAddonErrors = 2
+	// exitInterrupted indicates the user interrupted the command before it finished.
+	exitInterrupted = 130
+)
+
+var outputFormat string
+
+// exitCode is the code wadman will exit with once the command has finished.
+var exitCode = exitSuccess
+
+// structuredOutput determines whether the user has requested machine-readable output.
+func structuredOutput() bool {
+	return outputFormat != outputText
+}
+
+// messages returns the writer that informational messages should be written to. When structured output is requested
+// these are sent to stderr so they don't interfere with parsing.
+func messages() io.Writer {
+	if structuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// checkOutputFormat bails if the user has specified an unsupported output format.
+func checkOutputFormat() {
+	switch outputFormat {
+	case outputText, outputJson, outputYaml:
+	default:
+		bail("Unsupported output format '%s'. Valid formats are: text, json, yaml", outputFormat)
+	}
+}
+
+// writeOutput encodes the given value to stdout in the requested structured format.
+func writeOutput(v interface{}) {
+	var err error
+	switch outputFormat {
+	case outputJson:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(v)
+	case outputYaml:
+		err = yaml.NewEncoder(os.Stdout).Encode(v)
+	}
+
+	if err != nil {
+		bail("Unable to write output: %v", err)
+	}
+}
+
+// errorString converts an error to a string suitable for including in structured output.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// addonFailed records that an addon could not be processed, so wadman exits with the appropriate code.
+func addonFailed() {
+	exitCode = exitAddonErrors
+}
+
+// logf writes an informational message in the given format.
+func logf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(messages(), format, args...)
+}
@@ -24,11 +24,7 @@ var removeCommand = &cobra.Command{
 			addon := config.Addons[i]
 
 			if included[addon.ShortName()] {
-				if err := install.RemoveAddons(addon.Dirs()); err != nil {
-					fmt.Printf("Failed to delete addon '%s': %v\n", addon.DisplayName(), err)
-				} else {
-					fmt.Printf("Removed addon '%s'\n", addon.DisplayName())
-				}
+				removeAddonDirs(addon)
 			} else {
 				newAddons = append(newAddons, addon)
 			}
@@ -41,3 +37,15 @@ var removeCommand = &cobra.Command{
 		}
 	},
 }
+
+// removeAddonDirs deletes all of the directories belonging to the addon, returning true if they were all removed.
+func removeAddonDirs(addon wadman.Addon) bool {
+	if err := install.RemoveAddons(addon.Dirs()); err != nil {
+		fmt.Printf("Failed to delete addon '%s': %v\n", addon.DisplayName(), err)
+		addonFailed()
+		return false
+	}
+
+	fmt.Printf("Removed addon '%s'\n", addon.DisplayName())
+	return true
+}
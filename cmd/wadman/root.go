@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/csmith/wadman"
+	"github.com/csmith/wadman/filelock"
 	"github.com/csmith/wadman/wow"
 	"github.com/spf13/cobra"
 	"os"
+	"os/signal"
 	"sort"
 	"strings"
+	"syscall"
 )
 
 var (
@@ -19,20 +23,83 @@ var (
 	configPath string
 	config     *wadman.Config
 	install    *wow.Install
+
+	// ctx is cancelled when the user interrupts wadman. Commands should stop processing further addons when it is
+	// cancelled, but the addon currently being installed will either be finished or rolled back.
+	ctx    context.Context
+	cancel context.CancelFunc
 )
 
+// lockingAnnotation is set on commands that run for a long time, so they take the config and install locks around
+// each operation instead of holding them for the whole command.
+const lockingAnnotation = "wadman.locking"
+
+// locks are the config and install locks held by this process.
+var locks []*filelock.Lock
+
 func init() {
-	cobra.OnInitialize(loadConfig, createInstall)
+	cobra.OnInitialize(checkOutputFormat, trapInterrupts)
+	rootCommand.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if _, manual := cmd.Annotations[lockingAnnotation]; manual {
+			loadConfig()
+			createInstall()
+			return
+		}
+
+		findConfig()
+		if err := lock(configPath + ".lock"); err != nil {
+			bail("%v", err)
+		}
+		loadConfig()
+		createInstall()
+		if err := lock(install.LockPath()); err != nil {
+			bail("%v", err)
+		}
+	}
 }
 
-func loadConfig() {
-	var err error
+// trapInterrupts cancels the global context when wadman receives an interrupt signal. A second interrupt terminates
+// wadman immediately.
+func trapInterrupts() {
+	ctx, cancel = context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		logf("\nInterrupted: finishing up. Press Ctrl+C again to quit immediately.\n")
+		cancel()
+		<-signals
+		os.Exit(exitInterrupted)
+	}()
+}
 
+// interrupted determines whether the user has interrupted wadman, and if so sets the appropriate exit code.
+func interrupted() bool {
+	if ctx.Err() != nil {
+		exitCode = exitInterrupted
+		return true
+	}
+	return false
+}
+
+// findConfig determines where the config file is, if it hasn't been already.
+func findConfig() {
+	if configPath != "" {
+		return
+	}
+
+	var err error
 	configPath, err = wadman.ConfigPath()
 	if err != nil {
 		bail("Unable to build config path: %v", err)
 	}
+}
+
+func loadConfig() {
+	findConfig()
 
+	var err error
 	config, err = wadman.LoadConfig(configPath)
 	if err != nil {
 		bail("Unable to load config from %s: %v", configPath, err)
@@ -40,22 +107,151 @@ func loadConfig() {
 
 	if config.InstallPath == "" {
 		if path, ok := wow.GuessPath(); ok {
-			fmt.Printf("Detected WoW install at %s\n", path)
+			logf("Detected WoW install at %s\n", path)
 			config.InstallPath = path
 		} else {
 			bail("Unable to find WoW install. Please edit the config file manually: %s", configPath)
 		}
 	}
+
+	configureClients()
+}
+
+// lock takes the lock at the given path, returning an error that explains what to do if another copy of wadman holds
+// it.
+func lock(path string) error {
+	l, err := filelock.TryLock(path)
+	if err == filelock.ErrLocked {
+		return anotherRunningError{pid: filelock.Owner(path)}
+	} else if err != nil {
+		return fmt.Errorf("Unable to lock %s: %v", path, err)
+	}
+
+	locks = append(locks, l)
+	return nil
+}
+
+// anotherRunningError is returned when a lock is held by another copy of wadman.
+type anotherRunningError struct {
+	pid int
+}
+
+func (e anotherRunningError) Error() string {
+	if e.pid > 0 {
+		return fmt.Sprintf("Another wadman is running (process %d). Please try again once it has finished", e.pid)
+	}
+	return "Another wadman is running. Please try again once it has finished"
+}
+
+func (e anotherRunningError) Unwrap() error {
+	return filelock.ErrLocked
+}
+
+// acquireLocks takes the config and install locks for long-running commands, and reloads the config in case another
+// copy of wadman changed it. releaseLocks must be called once the operation has finished.
+func acquireLocks() error {
+	if err := lock(configPath + ".lock"); err != nil {
+		return err
+	}
+
+	if err := reloadConfig(); err != nil {
+		releaseLocks()
+		return err
+	}
+
+	if err := lock(install.LockPath()); err != nil {
+		releaseLocks()
+		return err
+	}
+	return nil
+}
+
+// releaseLocks releases all of the locks held by this process.
+func releaseLocks() {
+	for i := len(locks) - 1; i >= 0; i-- {
+		_ = locks[i].Unlock()
+	}
+	locks = nil
+}
+
+// reloadConfig reads the config file again, for long-running commands that need to pick up changes made by other
+// wadman commands. The install path detected at startup is kept if the file doesn't specify one.
+func reloadConfig() error {
+	latest, err := wadman.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("Unable to reload config from %s: %v", configPath, err)
+	}
+	if latest.InstallPath == "" {
+		latest.InstallPath = config.InstallPath
+	}
+	config = latest
+	configureClients()
+	createInstall()
+	return nil
+}
+
+// configureClients applies API keys and addresses from the config and environment to the API clients.
+func configureClients() {
+	wadman.WagoClient.ApiKey = config.WagoApiKey
+	if key := os.Getenv("WADMAN_WAGO_API_KEY"); key != "" {
+		wadman.WagoClient.ApiKey = key
+	}
+	if url := os.Getenv("WADMAN_WAGO_URL"); url != "" {
+		wadman.WagoClient.BaseUrl = url
+	}
+
+	wadman.WagoIoClient.ApiKey = config.WagoIoApiKey
+	if key := os.Getenv("WADMAN_WAGO_IO_API_KEY"); key != "" {
+		wadman.WagoIoClient.ApiKey = key
+	}
+	if url := os.Getenv("WADMAN_WAGO_IO_URL"); url != "" {
+		wadman.WagoIoClient.BaseUrl = url
+	}
 }
 
 func saveConfig() {
+	if err := writeConfig(); err != nil {
+		bail("%v", err)
+	}
+}
+
+// writeConfig saves the config file and lockfile, returning an error instead of exiting if they can't be written.
+func writeConfig() error {
+	if _, err := writeConfigFile(); err != nil {
+		return err
+	}
+
+	lockPath, err := wadman.LockfilePath()
+	if err != nil {
+		return fmt.Errorf("Unable to build lockfile path: %v", err)
+	}
+
+	if err := wadman.SaveLockfile(lockPath, wadman.NewLockfile(config.Addons)); err != nil {
+		return fmt.Errorf("Unable to save lockfile to %s: %v", lockPath, err)
+	}
+	return nil
+}
+
+// saveConfigOnly saves the config file without touching the lockfile, for commands that install from the lockfile
+// and so must never change it.
+func saveConfigOnly() {
+	if _, err := writeConfigFile(); err != nil {
+		bail("%v", err)
+	}
+}
+
+// writeConfigFile sorts the addons and saves the config file, returning the path the old file was backed up to if it
+// needed migrating.
+func writeConfigFile() (string, error) {
 	sort.Slice(config.Addons, func(i, j int) bool {
 		return strings.Compare(config.Addons[i].DisplayName(), config.Addons[j].DisplayName()) < 0
 	})
 
-	if err := wadman.SaveConfig(configPath, config); err != nil {
-		bail("Unable to save config file to %s: %v", configPath, err)
+	backup, err := wadman.UpgradeConfig(configPath, config)
+	if err != nil {
+		return "", fmt.Errorf("Unable to save config file to %s: %v", configPath, err)
 	}
+	return backup, nil
 }
 
 func createInstall() {
@@ -63,6 +259,6 @@ func createInstall() {
 }
 
 func bail(format string, args ...interface{}) {
-	fmt.Printf(fmt.Sprintf("%s\n", format), args)
-	os.Exit(1)
+	_, _ = fmt.Fprintf(os.Stderr, fmt.Sprintf("%s\n", format), args...)
+	os.Exit(exitFatal)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/csmith/wadman/wow/toc"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"os"
@@ -11,68 +12,97 @@ import (
 
 func init() {
 	rootCommand.AddCommand(scanCommand)
-	scanCommand.Flags().BoolVar(&skipLoadOnDemand, "skip-load-on-demand",  true, "Skip load-on-demand addons")
+	scanCommand.Flags().BoolVar(&skipLoadOnDemand, "skip-load-on-demand", true, "Skip load-on-demand addons")
 }
 
 var skipLoadOnDemand bool
 
+type scanRecord struct {
+	Directory string   `json:"directory" yaml:"directory"`
+	Ids       []string `json:"ids" yaml:"ids"`
+	Error     string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
 var scanCommand = &cobra.Command{
 	Use:   "scan",
 	Short: "Scans for existing addons in the WoW install",
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		addons, err := install.ListAddons()
+		records, err := scanRecords()
 		if err != nil {
 			bail("Unable to read addon directory: %v", err)
 		}
 
+		for _, record := range records {
+			if record.Error != "" {
+				addonFailed()
+			}
+		}
+
+		if structuredOutput() {
+			writeOutput(records)
+			return
+		}
+
 		addCmd := strings.Builder{}
 		table := tablewriter.NewWriter(os.Stdout)
 		table.SetHeader([]string{"Addon", "Scan result"})
 		table.SetAutoWrapText(false)
-		for i := range addons {
-			metadata, _, err := install.ReadToc(addons[i])
-			if err != nil {
-				table.Append([]string{addons[i], fmt.Sprintf("error: %v", err)})
-				continue
+		for _, record := range records {
+			if record.Error != "" {
+				table.Append([]string{record.Directory, fmt.Sprintf("error: %s", record.Error)})
+			} else if len(record.Ids) == 0 {
+				table.Append([]string{record.Directory, "unknown"})
+			} else {
+				addCmd.WriteString(" ")
+				addCmd.WriteString(record.Ids[0])
+				table.Append([]string{record.Directory, strings.Join(record.Ids, " ")})
 			}
+		}
+		table.Render()
+		if addCmd.Len() > 0 {
+			fmt.Printf("\nTo add all addons, run:\n\n\twadman add%s\n", addCmd.String())
+		}
+	},
+}
 
-			if (skipLoadOnDemand && metadata["loadondemand"] == "1") || metadata["x-part-of"] != "" {
-				continue
-			}
+// scanRecords reads the TOC file of every directory in the addons folder to find the IDs it could be installed with.
+func scanRecords() ([]scanRecord, error) {
+	addons, err := install.ListAddons()
+	if err != nil {
+		return nil, err
+	}
 
-			status := strings.Builder{}
-			if curse, ok := metadata["x-curse-project-id"]; ok {
-				if _, err := strconv.Atoi(curse); err == nil{
-					if status.Len() == 0 {
-						addCmd.WriteString(" curse:")
-						addCmd.WriteString(curse)
-					}
-					status.WriteString(" curse:")
-					status.WriteString(curse)
-				}
-			}
+	records := []scanRecord{}
+	for i := range addons {
+		t, err := toc.ForAddon(install, addons[i])
+		if err != nil {
+			records = append(records, scanRecord{Directory: addons[i], Ids: []string{}, Error: err.Error()})
+			continue
+		}
 
-			if wowi, ok := metadata["x-wowi-id"]; ok {
-				if _, err := strconv.Atoi(wowi); err == nil {
-					if status.Len() == 0 {
-						addCmd.WriteString(" wowi:")
-						addCmd.WriteString(wowi)
-					}
-					status.WriteString(" wowi:")
-					status.WriteString(wowi)
-				}
-			}
+		if (skipLoadOnDemand && t.LoadOnDemand) || t.Field("X-Part-Of") != "" {
+			continue
+		}
 
-			if status.Len() == 0 {
-				status.WriteString("unknown")
-			}
+		records = append(records, scanRecord{Directory: addons[i], Ids: tocIds(t)})
+	}
+	return records, nil
+}
 
-			table.Append([]string{addons[i], strings.TrimSpace(status.String())})
+// tocIds returns the IDs of the addon as specified in its TOC meta-data.
+func tocIds(t *toc.Toc) []string {
+	ids := []string{}
+	if curse := t.Field("X-Curse-Project-ID"); curse != "" {
+		if _, err := strconv.Atoi(curse); err == nil {
+			ids = append(ids, "curse:"+curse)
 		}
-		table.Render()
-		if addCmd.Len() > 0 {
-			fmt.Printf("\nTo add all addons, run:\n\n\twadman add%s\n", addCmd.String())
+	}
+
+	if wowi := t.Field("X-WoWI-ID"); wowi != "" {
+		if _, err := strconv.Atoi(wowi); err == nil {
+			ids = append(ids, "wowi:"+wowi)
 		}
-	},
+	}
+	return ids
 }
@@ -2,26 +2,146 @@ package main
 
 import (
 	"fmt"
-	"github.com/csmith/wadman/curse"
+	"github.com/csmith/wadman"
+	"github.com/csmith/wadman/wow"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func init() {
 	rootCommand.AddCommand(searchCommand)
+	searchCommand.Flags().StringSliceVarP(&searchSources, "source", "s", nil, "Only search the given sources (e.g. curse, wowi)")
+	searchCommand.Flags().StringVar(&searchFlavour, "flavour", "", "Only show addons supporting the given flavour (retail, classic, bcc, wrath)")
+	searchCommand.Flags().StringVar(&searchCategory, "category", "", "Only show addons in a matching category")
+	searchCommand.Flags().IntVarP(&searchLimit, "limit", "l", 20, "Maximum number of results to show (0 for unlimited)")
+	searchCommand.Flags().IntVarP(&searchInstall, "install", "i", 0, "Install the result with the given number")
+}
+
+var searchSources []string
+var searchFlavour string
+var searchCategory string
+var searchLimit int
+var searchInstall int
+
+type searchRecord struct {
+	Id          string        `json:"id" yaml:"id"`
+	Name        string        `json:"name" yaml:"name"`
+	Source      string        `json:"source" yaml:"source"`
+	Summary     string        `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Author      string        `json:"author,omitempty" yaml:"author,omitempty"`
+	Downloads   int64         `json:"downloads" yaml:"downloads"`
+	LastUpdated *time.Time    `json:"last_updated,omitempty" yaml:"last_updated,omitempty"`
+	Flavours    []wow.Flavour `json:"flavours" yaml:"flavours"`
+	Categories  []string      `json:"categories" yaml:"categories"`
 }
 
 var searchCommand = &cobra.Command{
 	Use:   "search <query>",
-	Short: "Search for available addons on CurseForge",
+	Short: "Search for available addons across all sources",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		results, err := curse.SearchAddons(args[0])
+		options := wadman.SearchOptions{
+			Category: searchCategory,
+		}
+		if searchFlavour != "" {
+			flavour, err := wow.ParseFlavour(searchFlavour)
+			if err != nil {
+				bail("%v", err)
+			}
+			options.Flavour = flavour
+		}
+		for _, s := range searchSources {
+			if _, err := wadman.ProviderFor(wadman.AddonType(s)); err != nil {
+				bail("Unknown source '%s'", s)
+			}
+			options.Sources = append(options.Sources, wadman.AddonType(s))
+		}
+
+		results, err := wadman.Search(ctx, args[0], options)
 		if err != nil {
-			bail("Unable to search addons: %v", err)
+			if len(results) == 0 {
+				bail("Unable to search addons: %v", err)
+			}
+			logf("Warning: %v\n", err)
+			addonFailed()
 		}
 
-		for i := range results {
-			fmt.Printf("[%6d] %s\n", results[i].Id, results[i].Name)
+		if searchLimit > 0 && len(results) > searchLimit {
+			results = results[:searchLimit]
+		}
+
+		if searchInstall > 0 {
+			if searchInstall > len(results) {
+				bail("There is no result number %d", searchInstall)
+			}
+
+			addon, err := wadman.NewAddon(results[searchInstall-1].Id)
+			if err != nil {
+				bail("Unable to install %s: %v", results[searchInstall-1].Id, err)
+			}
+
+			defer saveConfig()
+			addAddon(results[searchInstall-1].Id, addon)
+			return
+		}
+
+		if structuredOutput() {
+			records := []searchRecord{}
+			for _, r := range results {
+				record := searchRecord{
+					Id:         r.Id,
+					Name:       r.Name,
+					Source:     r.Source,
+					Summary:    r.Summary,
+					Author:     r.Author,
+					Downloads:  r.Downloads,
+					Flavours:   append([]wow.Flavour{}, r.Flavours...),
+					Categories: append([]string{}, r.Categories...),
+				}
+				if !r.LastUpdated.IsZero() {
+					lastUpdated := r.LastUpdated
+					record.LastUpdated = &lastUpdated
+				}
+				records = append(records, record)
+			}
+			writeOutput(records)
+			return
+		}
+
+		if len(results) == 0 {
+			fmt.Printf("No addons found\n")
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"#", "ID", "Name", "Author", "Downloads", "Last updated", "Flavours"})
+		table.SetAutoWrapText(false)
+		for i, r := range results {
+			var lastUpdated string
+			if !r.LastUpdated.IsZero() {
+				lastUpdated = r.LastUpdated.Format("2006-01-02")
+			}
+
+			var flavours []string
+			for _, f := range r.Flavours {
+				flavours = append(flavours, string(f))
+			}
+
+			table.Append([]string{
+				strconv.Itoa(i + 1),
+				r.Id,
+				r.Name,
+				r.Author,
+				strconv.FormatInt(r.Downloads, 10),
+				lastUpdated,
+				strings.Join(flavours, ", "),
+			})
 		}
+		table.Render()
+		fmt.Printf("\nTo install a result, run 'wadman add <id>' or repeat the search with '--install <#>'\n")
 	},
 }
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/csmith/wadman/filelock"
+	"github.com/spf13/cobra"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	rootCommand.AddCommand(serveCommand)
+	serveCommand.Flags().StringVar(&serveListen, "listen", defaultServeAddress, "Address to listen for HTTP requests on")
+	serveCommand.Flags().StringVar(&serveToken, "token", "", "Token that clients must supply (default from WADMAN_API_TOKEN or the config, generated if not set)")
+}
+
+const (
+	defaultServeAddress = "127.0.0.1:8421"
+	// shutdownTimeout is how long in-flight requests are given to finish when the server is interrupted.
+	shutdownTimeout = 30 * time.Second
+	// unknownSizeProgressInterval is how many bytes are downloaded between progress events when the size of a
+	// download isn't known.
+	unknownSizeProgressInterval = 1 << 20
+)
+
+var (
+	serveListen string
+	serveToken  string
+)
+
+var serveCommand = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API for managing addons from other programs",
+	Long: "Serve a REST API that allows other programs to list, add, remove and update addons. Clients must supply " +
+		"the API token in an 'Authorization: Bearer' header or a 'token' query parameter.",
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{lockingAnnotation: "per-request"},
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := apiToken()
+		if err != nil {
+			bail("Unable to create API token: %v", err)
+		}
+
+		server := &http.Server{Addr: serveListen, Handler: (&apiServer{token: token}).routes()}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+
+		logf("Listening on http://%s/api/\n", serveListen)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			bail("Unable to serve API: %v", err)
+		}
+	},
+}
+
+// apiToken returns the token clients must supply, from the command line flag, the environment or the config. If
+// none is set, a random token is generated and saved to the config.
+func apiToken() (string, error) {
+	switch {
+	case serveToken != "":
+		return serveToken, nil
+	case os.Getenv("WADMAN_API_TOKEN") != "":
+		return os.Getenv("WADMAN_API_TOKEN"), nil
+	case config.ApiToken != "":
+		return config.ApiToken, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	if err := acquireLocks(); err != nil {
+		return "", err
+	}
+	defer releaseLocks()
+
+	config.ApiToken = hex.EncodeToString(b)
+	if err := writeConfig(); err != nil {
+		return "", err
+	}
+	fmt.Printf("Generated API token: %s\n", config.ApiToken)
+	return config.ApiToken, nil
+}
+
+// apiServer handles requests to the REST API. Requests are handled one at a time, so that they don't make
+// conflicting changes to the config or the addons directory.
+type apiServer struct {
+	token string
+	lock  sync.Mutex
+}
+
+type apiHandler func(w http.ResponseWriter, r *http.Request)
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/api/addons", s.route(map[string]apiHandler{
+		http.MethodGet:    s.listAddons,
+		http.MethodPost:   s.addAddons,
+		http.MethodDelete: s.removeAddons,
+	}))
+	mux.Handle("/api/outdated", s.route(map[string]apiHandler{http.MethodGet: s.outdatedAddons}))
+	mux.Handle("/api/update", s.route(map[string]apiHandler{http.MethodPost: s.updateAddons}))
+	mux.Handle("/api/scan", s.route(map[string]apiHandler{http.MethodGet: s.scanAddons}))
+	return mux
+}
+
+// route checks the request is authorised and dispatches it to the handler for its method. The config and install
+// are locked for each request, and the config is reloaded in case it has been changed by other wadman commands.
+func (s *apiServer) route(handlers map[string]apiHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorised(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wadman"`)
+			writeApiError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+
+		handler, ok := handlers[r.Method]
+		if !ok {
+			var allowed []string
+			for method := range handlers {
+				allowed = append(allowed, method)
+			}
+			sort.Strings(allowed)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeApiError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		if err := acquireLocks(); errors.Is(err, filelock.ErrLocked) {
+			writeApiError(w, http.StatusConflict, err.Error())
+			return
+		} else if err != nil {
+			writeApiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer releaseLocks()
+		handler(w, r)
+	})
+}
+
+// authorised checks the token in the request's Authorization header or, for clients such as EventSource that can't
+// set headers, its query string.
+func (s *apiServer) authorised(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// listAddons handles GET /api/addons, optionally filtered to a single character with the 'character' parameter.
+func (s *apiServer) listAddons(w http.ResponseWriter, r *http.Request) {
+	character := r.URL.Query().Get("character")
+	characters, err := selectCharacters(character)
+	if err != nil && character != "" {
+		writeApiError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	records, _, _ := listRecords(characters)
+	writeApiResponse(w, http.StatusOK, records)
+}
+
+type addRequest struct {
+	Ids  []string `json:"ids"`
+	Link bool     `json:"link"`
+}
+
+type addRecord struct {
+	Id      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// addAddons handles POST /api/addons, installing each of the addons in the request body.
+func (s *apiServer) addAddons(w http.ResponseWriter, r *http.Request) {
+	request := &addRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil || len(request.Ids) == 0 {
+		writeApiError(w, http.StatusBadRequest, "request body must contain a list of ids")
+		return
+	}
+
+	records := []addRecord{}
+	for _, id := range request.Ids {
+		if ctx.Err() != nil {
+			break
+		}
+
+		record := addRecord{Id: id}
+		addon, err := wadman.NewAddon(id)
+		if err == nil {
+			record.Id = addon.ShortName()
+			if d, ok := addon.(*wadman.DirectoryAddon); ok {
+				d.Link = request.Link
+			}
+
+			if addonExists(addon.ShortName()) {
+				err = fmt.Errorf("addon is already installed")
+			} else if _, err = wadman.Update(ctx, addon, install, nil, false); err == nil {
+				config.Addons = append(config.Addons, addon)
+				record.Name = addon.DisplayName()
+				record.Version = addon.CurrentVersion()
+			}
+		}
+		record.Error = errorString(err)
+		records = append(records, record)
+	}
+
+	if err := writeConfig(); err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeApiResponse(w, http.StatusOK, records)
+}
+
+type removeRecord struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// removeAddons handles DELETE /api/addons, removing the addons given in the 'id' parameter (which may be repeated).
+// Addons whose directories can't be deleted are left in the config.
+func (s *apiServer) removeAddons(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query()["id"]
+	if len(ids) == 0 {
+		writeApiError(w, http.StatusBadRequest, "at least one id must be given")
+		return
+	}
+
+	selected := make(map[wadman.Addon]bool)
+	for _, addon := range selectAddons(ids) {
+		selected[addon] = true
+	}
+	if len(selected) == 0 {
+		writeApiError(w, http.StatusNotFound, "no matching addons found")
+		return
+	}
+
+	records := []removeRecord{}
+	var remaining []wadman.Addon
+	for _, addon := range config.Addons {
+		if !selected[addon] {
+			remaining = append(remaining, addon)
+			continue
+		}
+
+		err := install.RemoveAddons(addon.Dirs())
+		if err != nil {
+			remaining = append(remaining, addon)
+		}
+		records = append(records, removeRecord{Id: addon.ShortName(), Name: addon.DisplayName(), Error: errorString(err)})
+	}
+	config.Addons = remaining
+
+	if err := writeConfig(); err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeApiResponse(w, http.StatusOK, records)
+}
+
+// outdatedAddons handles GET /api/outdated. The addons to check can be limited with the 'id' parameter, and
+// changelogs are included if 'changelog' is true.
+func (s *apiServer) outdatedAddons(w http.ResponseWriter, r *http.Request) {
+	changelog := r.URL.Query().Get("changelog") == "true"
+
+	records := []outdatedRecord{}
+	for _, addon := range selectAddons(r.URL.Query()["id"]) {
+		if ctx.Err() != nil || r.Context().Err() != nil {
+			break
+		}
+		records = append(records, checkOutdated(addon, nil, changelog))
+	}
+	writeApiResponse(w, http.StatusOK, records)
+}
+
+type updateRequest struct {
+	Ids       []string `json:"ids"`
+	Force     bool     `json:"force"`
+	Changelog bool     `json:"changelog"`
+}
+
+// updateAddons handles POST /api/update. If the client accepts an event stream, progress is sent as server-sent
+// events while the addons are updated, followed by a 'finished' event containing the results. Otherwise the results
+// are returned once all addons have been updated.
+func (s *apiServer) updateAddons(w http.ResponseWriter, r *http.Request) {
+	request := &updateRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			writeApiError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+	}
+
+	var stream *eventStream
+	var observer wadman.Observer
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		var err error
+		if stream, err = newEventStream(w); err != nil {
+			writeApiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		observer = stream.observer()
+	}
+
+	records := []updateRecord{}
+	for _, addon := range selectAddons(request.Ids) {
+		// Stop between addons if the client goes away, rather than updating with nobody watching
+		if ctx.Err() != nil || r.Context().Err() != nil {
+			break
+		}
+
+		record, _ := updateAddon(addon, observer, request.Force, request.Changelog)
+		records = append(records, record)
+		if stream != nil {
+			stream.send("result", record)
+		}
+	}
+
+	err := writeConfig()
+	notifyWebhooks(records, logf)
+	if stream == nil && err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+	} else if stream == nil {
+		writeApiResponse(w, http.StatusOK, records)
+	} else if err != nil {
+		stream.send("error", map[string]string{"error": err.Error()})
+	} else {
+		stream.send("finished", records)
+	}
+}
+
+// scanAddons handles GET /api/scan.
+func (s *apiServer) scanAddons(w http.ResponseWriter, r *http.Request) {
+	records, err := scanRecords()
+	if err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeApiResponse(w, http.StatusOK, records)
+}
+
+func writeApiResponse(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeApiError(w http.ResponseWriter, status int, message string) {
+	writeApiResponse(w, status, map[string]string{"error": message})
+}
+
+// eventStream writes server-sent events to a client.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	lock    sync.Mutex
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &eventStream{w: w, flusher: flusher}, nil
+}
+
+// send writes an event with the given name and JSON-encoded data. Errors are ignored, as they only occur if the
+// client has disconnected.
+func (s *eventStream) send(name string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, _ = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, b)
+	s.flusher.Flush()
+}
+
+// observer returns an Observer that sends library events to the client. Download progress is sent at most once per
+// percent, so that large downloads don't flood the stream.
+func (s *eventStream) observer() wadman.Observer {
+	progress := make(map[wadman.Addon]int64)
+	return func(event wadman.Event) {
+		id := event.Subject().ShortName()
+		switch e := event.(type) {
+		case wadman.CheckingEvent:
+			s.send("checking", map[string]interface{}{"addon": id})
+
+		case wadman.ResolvedEvent:
+			s.send("resolved", map[string]interface{}{
+				"addon":   id,
+				"file_id": e.Release.FileId,
+				"version": e.Release.Version,
+				"newer":   e.Newer,
+			})
+
+		case wadman.DownloadProgressEvent:
+			step := int64(unknownSizeProgressInterval)
+			if e.Total > 0 {
+				step = e.Total / 100
+			}
+			if step > 0 && e.Bytes/step <= progress[e.Addon] && e.Bytes != e.Total {
+				return
+			}
+			if step > 0 {
+				progress[e.Addon] = e.Bytes / step
+			}
+			s.send("progress", map[string]interface{}{"addon": id, "bytes": e.Bytes, "total": e.Total})
+
+		case wadman.ExtractedEvent:
+			s.send("extracted", map[string]interface{}{"addon": id, "directories": e.Directories})
+
+		case wadman.DoneEvent:
+			s.send("done", map[string]interface{}{"addon": id, "updated": e.Updated, "pinned": e.Pinned})
+
+		case wadman.FailedEvent:
+			s.send("failed", map[string]interface{}{"addon": id, "error": errorString(e.Err)})
+		}
+	}
+}
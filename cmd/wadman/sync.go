@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/spf13/cobra"
+	"path/filepath"
+)
+
+func init() {
+	rootCommand.AddCommand(syncCommand)
+	syncCommand.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Show the changes that would be made without applying them")
+}
+
+var dryRun bool
+
+var syncCommand = &cobra.Command{
+	Use:   "sync [file|url|-]",
+	Short: "Make installed addons match an addon list",
+	Long: "Installs, removes and reconfigures addons so they exactly match the given addon list. If no list is " +
+		"given, addons.json in the WoW install directory is used.",
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		source := filepath.Join(config.InstallPath, "addons.json")
+		if len(args) == 1 {
+			source = args[0]
+		}
+
+		list, err := readAddonList(source)
+		if err != nil {
+			bail("Unable to read addon list from %s: %v", source, err)
+		}
+
+		plan := list.Plan(config.Addons)
+		if plan.Empty() {
+			fmt.Printf("Installed addons already match %s\n", source)
+			return
+		}
+
+		printPlan(plan)
+		if dryRun {
+			return
+		}
+
+		fmt.Println()
+		defer saveConfig()
+
+		removed := make(map[wadman.Addon]bool)
+		for _, addon := range plan.Remove {
+			removed[addon] = removeAddonDirs(addon)
+		}
+
+		var addons []wadman.Addon
+		for i := range config.Addons {
+			if !removed[config.Addons[i]] {
+				addons = append(addons, config.Addons[i])
+			}
+		}
+		config.Addons = addons
+
+		for _, change := range plan.Change {
+			change.Addon.SetChannel(change.Entry.Channel)
+			if err := pinAddon(change.Addon, change.Entry.Pin); err != nil {
+				fmt.Printf("Unable to pin addon '%s': %v\n", change.Addon.DisplayName(), err)
+				addonFailed()
+			} else {
+				fmt.Printf("Updated settings for addon '%s' (version %s)\n", change.Addon.DisplayName(), change.Addon.CurrentVersion())
+			}
+		}
+
+		for _, entry := range plan.Install {
+			if interrupted() {
+				break
+			}
+
+			addon, err := wadman.NewAddon(entry.Id)
+			if err != nil {
+				fmt.Printf("%s: %v\n", entry.Id, err)
+				addonFailed()
+				continue
+			}
+
+			addon.SetChannel(entry.Channel)
+			addPinnedAddon(entry.Id, addon, entry.Pin)
+		}
+	},
+}
+
+// printPlan describes the changes in the given plan.
+func printPlan(plan *wadman.SyncPlan) {
+	fmt.Printf("Planned changes:\n\n")
+	for _, entry := range plan.Install {
+		fmt.Printf("\t+ install %s%s\n", entry.Id, describeSettings(entry.Channel, entry.Pin))
+	}
+
+	for _, addon := range plan.Remove {
+		fmt.Printf("\t- remove %s (%s)\n", addon.ShortName(), addon.DisplayName())
+	}
+
+	for _, change := range plan.Change {
+		fmt.Printf("\t~ change %s (%s)\n", change.Addon.ShortName(), change.Addon.DisplayName())
+		if change.ChannelChanged() {
+			fmt.Printf("\t\tchannel: %s => %s\n", channelName(change.Addon.Channel()), channelName(change.Entry.Channel))
+		}
+		if change.PinChanged() {
+			fmt.Printf("\t\tpin: %s => %s\n", pinName(change.Addon.Pin()), pinName(change.Entry.Pin))
+		}
+	}
+}
+
+func describeSettings(channel wadman.Channel, pin string) string {
+	var res string
+	if channel != wadman.ChannelDefault {
+		res += fmt.Sprintf(" [channel: %s]", channel)
+	}
+	if pin != "" {
+		res += fmt.Sprintf(" [pin: %s]", pin)
+	}
+	return res
+}
+
+func channelName(channel wadman.Channel) string {
+	if channel == wadman.ChannelDefault {
+		return "default"
+	}
+	return string(channel)
+}
+
+func pinName(pin string) string {
+	if pin == "" {
+		return "none"
+	}
+	return pin
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+	"os"
+	"unicode/utf8"
+)
+
+// keyCode identifies a key that was pressed in the terminal. Printable characters are reported as keyRune.
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyTab
+	keyInterrupt
+	keyUnknown
+)
+
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// escapeSequences maps the escape sequences sent by common terminals to the keys they represent.
+var escapeSequences = map[string]keyCode{
+	"\x1b[A":  keyUp,
+	"\x1b[B":  keyDown,
+	"\x1b[C":  keyRight,
+	"\x1b[D":  keyLeft,
+	"\x1bOA":  keyUp,
+	"\x1bOB":  keyDown,
+	"\x1bOC":  keyRight,
+	"\x1bOD":  keyLeft,
+	"\x1b[5~": keyPageUp,
+	"\x1b[6~": keyPageDown,
+	"\x1b[H":  keyHome,
+	"\x1b[F":  keyEnd,
+	"\x1b[1~": keyHome,
+	"\x1b[4~": keyEnd,
+	"\x1bOH":  keyHome,
+	"\x1bOF":  keyEnd,
+}
+
+// Styles that can be applied to lines drawn on the screen.
+const (
+	styleNormal  = ""
+	styleBold    = "\x1b[1m"
+	styleReverse = "\x1b[7m"
+	styleDim     = "\x1b[2m"
+	styleReset   = "\x1b[0m"
+)
+
+// terminal draws full-screen output and reads key presses, with the terminal in raw mode.
+type terminal struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+	lines []string
+}
+
+// openTerminal switches the terminal to raw mode and the alternate screen. close must be called to restore it.
+func openTerminal() (*terminal, error) {
+	in, out := os.Stdin, os.Stdout
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, fmt.Errorf("not running in a terminal")
+	}
+
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+
+	_, _ = out.WriteString("\x1b[?1049h\x1b[?25l")
+	return &terminal{in: in, out: out, state: state}, nil
+}
+
+func (t *terminal) close() {
+	_, _ = t.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	_ = term.Restore(int(t.in.Fd()), t.state)
+}
+
+// size returns the width and height of the terminal, falling back to 80x24 if it can't be determined.
+func (t *terminal) size() (int, int) {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// readKeys sends each key pressed to the given channel until reading from the terminal fails.
+func (t *terminal) readKeys(keys chan<- key) {
+	buf := make([]byte, 64)
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+
+		for _, k := range decodeKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// decodeKeys converts the bytes read from the terminal into key presses. Escape sequences are expected to arrive in
+// a single read, which is the case for all common terminals.
+func decodeKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			if len(b) == 1 {
+				return append(keys, key{code: keyEscape})
+			}
+
+			matched := false
+			for seq, code := range escapeSequences {
+				if bytes.HasPrefix(b, []byte(seq)) {
+					keys = append(keys, key{code: code})
+					b = b[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// Skip the rest of an unrecognised sequence
+				return append(keys, key{code: keyUnknown})
+			}
+			continue
+		}
+
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, key{code: keyEnter})
+		case 0x7f, 0x08:
+			keys = append(keys, key{code: keyBackspace})
+		case '\t':
+			keys = append(keys, key{code: keyTab})
+		case 0x03:
+			keys = append(keys, key{code: keyInterrupt})
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r >= ' ' {
+				keys = append(keys, key{code: keyRune, r: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// clear starts a new frame.
+func (t *terminal) clear() {
+	t.lines = t.lines[:0]
+}
+
+// print adds a line to the frame, truncated or padded to the given width.
+func (t *terminal) print(width int, style string, text string) {
+	text = runewidth.FillRight(runewidth.Truncate(text, width, "…"), width)
+	if style != styleNormal {
+		text = style + text + styleReset
+	}
+	t.lines = append(t.lines, text)
+}
+
+// flush draws the frame, filling the rest of the screen with blank lines.
+func (t *terminal) flush(height int) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("\x1b[H")
+	for i := 0; i < height; i++ {
+		if i < len(t.lines) {
+			buf.WriteString(t.lines[i])
+		}
+		buf.WriteString("\x1b[K")
+		if i < height-1 {
+			buf.WriteString("\r\n")
+		}
+	}
+	_, _ = t.out.Write(buf.Bytes())
+}
+
+// column truncates or pads the text to exactly the given width, leaving a space before the next column.
+func column(text string, width int) string {
+	if width <= 1 {
+		return ""
+	}
+	return runewidth.FillRight(runewidth.Truncate(text, width-1, "…"), width)
+}
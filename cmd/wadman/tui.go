@@ -0,0 +1,782 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/spf13/cobra"
+	"strings"
+	"time"
+)
+
+func init() {
+	rootCommand.AddCommand(tuiCommand)
+	tuiCommand.Flags().IntVarP(&tuiParallelism, "parallel", "p", 4, "Number of addons to check or update at the same time")
+}
+
+var tuiParallelism int
+
+// channelCycle is the order channels are switched through when changing an addon's channel.
+var channelCycle = []wadman.Channel{wadman.ChannelDefault, wadman.ChannelRelease, wadman.ChannelBeta, wadman.ChannelAlpha}
+
+const tuiHelp = "↑↓ move  space select  a all  u update  d remove  p pin  c channel  r recheck  / search  q quit"
+
+var tuiCommand = &cobra.Command{
+	Use:   "tui",
+	Short: "Manage addons with an interactive terminal interface",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if tuiParallelism < 1 {
+			bail("--parallel must be at least 1")
+		}
+
+		t, err := openTerminal()
+		if err != nil {
+			bail("Unable to start interactive interface: %v", err)
+		}
+
+		ui := newTui(t)
+		ui.run()
+		t.close()
+
+		if ui.err != nil {
+			bail("%v", ui.err)
+		}
+	},
+}
+
+type tuiMode int
+
+const (
+	modeAddons tuiMode = iota
+	modeSearchInput
+	modeResults
+	modeConfirmRemove
+)
+
+// tuiRow is an addon shown in the interface. Rows are only modified by the interface's main loop; while busy is
+// set, a background job owns the addon and it must not be read or changed.
+type tuiRow struct {
+	addon    wadman.Addon
+	id       string
+	name     string
+	version  string
+	settings string
+	status   string
+	selected bool
+	busy     bool
+	outdated bool
+}
+
+// refresh copies the addon's details into the row, so they can be displayed without touching the addon.
+func (r *tuiRow) refresh() {
+	r.id = r.addon.ShortName()
+	r.name = r.addon.DisplayName()
+	r.version = r.addon.CurrentVersion()
+	r.settings = strings.TrimSpace(describeSettings(r.addon.Channel(), r.addon.Pin()))
+}
+
+// tui is the state of the interactive interface. All of its fields are owned by the main loop: background jobs
+// report back by posting functions that are run on the main loop.
+type tui struct {
+	term    *terminal
+	mode    tuiMode
+	rows    []*tuiRow
+	cursor  int
+	offset  int
+	message string
+	err     error
+
+	query         string
+	results       []wadman.SearchResult
+	resultCursor  int
+	resultOffset  int
+	searching     bool
+	pendingRemove []*tuiRow
+
+	posts     chan func()
+	semaphore chan struct{}
+	active    int
+	dirty     bool
+	quitting  bool
+}
+
+func newTui(t *terminal) *tui {
+	ui := &tui{
+		term:      t,
+		posts:     make(chan func(), 256),
+		semaphore: make(chan struct{}, tuiParallelism),
+	}
+
+	for _, addon := range config.Addons {
+		row := &tuiRow{addon: addon}
+		row.refresh()
+		ui.rows = append(ui.rows, row)
+	}
+	return ui
+}
+
+// run processes key presses and the results of background jobs until the user quits. Jobs that are still running
+// are cancelled, and the config is saved once they have finished.
+func (ui *tui) run() {
+	keys := make(chan key)
+	go ui.term.readKeys(keys)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	ui.checkAll()
+	ui.render()
+	for !ui.quitting || ui.active > 0 {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				ui.quit()
+				keys = nil
+				continue
+			}
+			ui.handleKey(k)
+		case post := <-ui.posts:
+			post()
+			// Apply everything that is waiting before redrawing, so progress updates don't cause a redraw each
+			for drained := false; !drained; {
+				select {
+				case post := <-ui.posts:
+					post()
+				default:
+					drained = true
+				}
+			}
+		case <-ticker.C:
+			// Redraw regularly in case the terminal has been resized
+		}
+		ui.render()
+	}
+	ui.save()
+}
+
+// quit stops accepting input and cancels any background jobs.
+func (ui *tui) quit() {
+	ui.quitting = true
+	if ui.active > 0 {
+		ui.message = fmt.Sprintf("Waiting for %d jobs to stop...", ui.active)
+		cancel()
+	}
+}
+
+// save writes the config if anything has changed and no jobs are running.
+func (ui *tui) save() {
+	if !ui.dirty || ui.active > 0 {
+		return
+	}
+
+	if err := writeConfig(); err != nil {
+		if ui.quitting {
+			ui.err = err
+		} else {
+			ui.message = err.Error()
+		}
+		return
+	}
+	ui.dirty = false
+}
+
+// start runs the job for the row in the background, limiting how many jobs run at the same time. The job's result
+// is run on the main loop once it has finished.
+func (ui *tui) start(row *tuiRow, status string, job func() func()) {
+	row.busy = true
+	row.status = status
+	ui.active++
+	go func() {
+		ui.semaphore <- struct{}{}
+		result := job()
+		<-ui.semaphore
+		ui.posts <- func() {
+			row.busy = false
+			ui.active--
+			result()
+			row.refresh()
+			ui.save()
+		}
+	}()
+}
+
+// post runs the function on the main loop. It may be called from background jobs.
+func (ui *tui) post(f func()) {
+	ui.posts <- f
+}
+
+func (ui *tui) checkAll() {
+	for _, row := range ui.rows {
+		ui.check(row)
+	}
+}
+
+// check looks for an update to the row's addon in the background.
+func (ui *tui) check(row *tuiRow) {
+	if row.busy {
+		return
+	}
+
+	addon := row.addon
+	ui.start(row, "checking...", func() func() {
+		if addon.Pin() != "" {
+			return func() {
+				row.outdated = false
+				row.status = "pinned"
+			}
+		}
+
+		latest, newer, err := wadman.CheckForUpdate(ctx, addon, install, nil)
+		return func() {
+			row.outdated = newer
+			switch {
+			case err != nil:
+				row.status = fmt.Sprintf("error: %v", err)
+			case newer:
+				row.status = fmt.Sprintf("update: %s", latest.Version)
+			default:
+				row.status = "up to date"
+			}
+		}
+	})
+}
+
+// update installs the latest version of the row's addon in the background, showing its progress.
+func (ui *tui) update(row *tuiRow) {
+	if row.busy {
+		return
+	}
+
+	addon := row.addon
+	ui.start(row, "queued", func() func() {
+		observer := ui.progressObserver(row)
+		updated, err := wadman.Update(ctx, addon, install, observer, false)
+		return func() {
+			switch {
+			case err != nil:
+				row.status = fmt.Sprintf("error: %v", err)
+			case updated:
+				row.outdated = false
+				row.status = "updated"
+				ui.dirty = true
+			case addon.Pin() != "":
+				row.status = "pinned"
+			default:
+				row.outdated = false
+				row.status = "up to date"
+			}
+		}
+	})
+}
+
+// progressObserver returns an Observer that shows the progress of a background job in the row's status. Download
+// progress is only posted when the percentage changes.
+func (ui *tui) progressObserver(row *tuiRow) wadman.Observer {
+	lastPercent := int64(-1)
+	return func(event wadman.Event) {
+		switch e := event.(type) {
+		case wadman.CheckingEvent:
+			ui.post(func() { row.status = "checking..." })
+		case wadman.ResolvedEvent:
+			if e.Newer {
+				version := e.Release.Version
+				ui.post(func() { row.status = fmt.Sprintf("downloading %s", version) })
+			}
+		case wadman.DownloadProgressEvent:
+			if e.Total <= 0 {
+				return
+			}
+			if percent := e.Bytes * 100 / e.Total; percent != lastPercent {
+				lastPercent = percent
+				ui.post(func() { row.status = fmt.Sprintf("downloading %d%%", percent) })
+			}
+		case wadman.ExtractedEvent:
+			ui.post(func() { row.status = "installing..." })
+		}
+	}
+}
+
+// install adds the search result as a new addon in the background.
+func (ui *tui) install(result wadman.SearchResult) {
+	for _, row := range ui.rows {
+		if row.id == result.Id {
+			ui.message = fmt.Sprintf("%s is already installed", result.Name)
+			return
+		}
+	}
+
+	addon, err := wadman.NewAddon(result.Id)
+	if err != nil {
+		ui.message = fmt.Sprintf("Unable to install %s: %v", result.Name, err)
+		return
+	}
+
+	row := &tuiRow{addon: addon, id: addon.ShortName(), name: result.Name}
+	ui.rows = append(ui.rows, row)
+	ui.message = fmt.Sprintf("Installing %s", result.Name)
+	ui.start(row, "queued", func() func() {
+		_, err := wadman.Update(ctx, addon, install, ui.progressObserver(row), false)
+		return func() {
+			if err != nil {
+				ui.message = fmt.Sprintf("Unable to install %s: %v", result.Name, err)
+				ui.removeRow(row)
+				return
+			}
+
+			config.Addons = append(config.Addons, addon)
+			row.status = "installed"
+			ui.dirty = true
+		}
+	})
+}
+
+// search runs the query across all sources in the background, then shows the results.
+func (ui *tui) search(query string) {
+	ui.searching = true
+	ui.message = fmt.Sprintf("Searching for '%s'...", query)
+	go func() {
+		results, err := wadman.Search(ctx, query, wadman.SearchOptions{})
+		ui.post(func() {
+			ui.searching = false
+			ui.results = results
+			ui.resultCursor, ui.resultOffset = 0, 0
+			switch {
+			case err != nil && len(results) == 0:
+				ui.message = fmt.Sprintf("Search failed: %v", err)
+				return
+			case err != nil:
+				ui.message = fmt.Sprintf("Warning: %v", err)
+			case len(results) == 0:
+				ui.message = fmt.Sprintf("No addons found matching '%s'", query)
+				return
+			default:
+				ui.message = fmt.Sprintf("%d results for '%s'", len(results), query)
+			}
+			ui.mode = modeResults
+		})
+	}()
+}
+
+// targets returns the selected rows, or the row under the cursor if none are selected.
+func (ui *tui) targets() []*tuiRow {
+	var res []*tuiRow
+	for _, row := range ui.rows {
+		if row.selected {
+			res = append(res, row)
+		}
+	}
+	if len(res) == 0 && ui.cursor < len(ui.rows) {
+		res = append(res, ui.rows[ui.cursor])
+	}
+	return res
+}
+
+// idle filters the rows to those without a running job, noting in the message line if any were skipped.
+func (ui *tui) idle(rows []*tuiRow) []*tuiRow {
+	var res []*tuiRow
+	for _, row := range rows {
+		if !row.busy {
+			res = append(res, row)
+		}
+	}
+	if len(res) < len(rows) {
+		ui.message = fmt.Sprintf("Skipped %d addons that are busy", len(rows)-len(res))
+	}
+	return res
+}
+
+func (ui *tui) clearSelection() {
+	for _, row := range ui.rows {
+		row.selected = false
+	}
+}
+
+// remove deletes the rows' addons and removes them from the config.
+func (ui *tui) remove(rows []*tuiRow) {
+	removed := 0
+	for _, row := range rows {
+		if err := install.RemoveAddons(row.addon.Dirs()); err != nil {
+			ui.message = fmt.Sprintf("Unable to remove %s: %v", row.name, err)
+			continue
+		}
+
+		var addons []wadman.Addon
+		for _, addon := range config.Addons {
+			if addon != row.addon {
+				addons = append(addons, addon)
+			}
+		}
+		config.Addons = addons
+		ui.removeRow(row)
+		removed++
+	}
+
+	if removed > 0 {
+		ui.dirty = true
+		ui.message = fmt.Sprintf("Removed %d addons", removed)
+		ui.save()
+	}
+}
+
+func (ui *tui) removeRow(row *tuiRow) {
+	for i := range ui.rows {
+		if ui.rows[i] == row {
+			ui.rows = append(ui.rows[:i], ui.rows[i+1:]...)
+			break
+		}
+	}
+	if ui.cursor >= len(ui.rows) && ui.cursor > 0 {
+		ui.cursor = len(ui.rows) - 1
+	}
+}
+
+// togglePin pins the rows' addons to their installed files, or unpins them if they are all pinned already.
+func (ui *tui) togglePin(rows []*tuiRow) {
+	pin := false
+	for _, row := range rows {
+		pin = pin || row.addon.Pin() == ""
+	}
+
+	for _, row := range rows {
+		if pin {
+			row.addon.SetPin(row.addon.Locked().FileId)
+			row.outdated = false
+			row.status = "pinned"
+		} else {
+			row.addon.SetPin("")
+		}
+		row.refresh()
+	}
+
+	ui.dirty = true
+	ui.save()
+	if !pin {
+		for _, row := range rows {
+			ui.check(row)
+		}
+	}
+}
+
+// cycleChannel moves the rows' addons to the next release channel, based on the channel of the first one, and
+// checks them for updates on the new channel.
+func (ui *tui) cycleChannel(rows []*tuiRow) {
+	if len(rows) == 0 {
+		return
+	}
+
+	next := channelCycle[0]
+	for i, c := range channelCycle {
+		if c == rows[0].addon.Channel() {
+			next = channelCycle[(i+1)%len(channelCycle)]
+		}
+	}
+
+	for _, row := range rows {
+		row.addon.SetChannel(next)
+		row.refresh()
+	}
+	ui.message = fmt.Sprintf("Switched %d addons to the %s channel", len(rows), channelName(next))
+	ui.dirty = true
+	ui.save()
+
+	for _, row := range rows {
+		ui.check(row)
+	}
+}
+
+func (ui *tui) handleKey(k key) {
+	if k.code == keyInterrupt {
+		ui.quit()
+		return
+	}
+	if ui.quitting {
+		return
+	}
+
+	switch ui.mode {
+	case modeAddons:
+		ui.handleAddonsKey(k)
+	case modeSearchInput:
+		ui.handleSearchInputKey(k)
+	case modeResults:
+		ui.handleResultsKey(k)
+	case modeConfirmRemove:
+		if k.code == keyRune && (k.r == 'y' || k.r == 'Y') {
+			ui.remove(ui.idle(ui.pendingRemove))
+			ui.clearSelection()
+		} else {
+			ui.message = ""
+		}
+		ui.pendingRemove = nil
+		ui.mode = modeAddons
+	}
+}
+
+func (ui *tui) handleAddonsKey(k key) {
+	_, height := ui.term.size()
+	page := listHeight(height)
+
+	switch k.code {
+	case keyUp, keyDown, keyPageUp, keyPageDown, keyHome, keyEnd:
+		ui.cursor = moveCursor(ui.cursor, len(ui.rows), page, k.code)
+		return
+	case keyEscape:
+		ui.clearSelection()
+		return
+	case keyRune:
+	default:
+		return
+	}
+
+	switch k.r {
+	case 'q':
+		ui.quit()
+	case 'j':
+		ui.cursor = moveCursor(ui.cursor, len(ui.rows), page, keyDown)
+	case 'k':
+		ui.cursor = moveCursor(ui.cursor, len(ui.rows), page, keyUp)
+	case ' ':
+		if ui.cursor < len(ui.rows) {
+			ui.rows[ui.cursor].selected = !ui.rows[ui.cursor].selected
+			ui.cursor = moveCursor(ui.cursor, len(ui.rows), page, keyDown)
+		}
+	case 'a':
+		all := true
+		for _, row := range ui.rows {
+			all = all && row.selected
+		}
+		for _, row := range ui.rows {
+			row.selected = !all
+		}
+	case 'o':
+		// Select everything with an update available
+		for _, row := range ui.rows {
+			row.selected = row.outdated
+		}
+	case 'u':
+		rows := ui.idle(ui.targets())
+		for _, row := range rows {
+			ui.update(row)
+		}
+		ui.clearSelection()
+	case 'd':
+		if rows := ui.idle(ui.targets()); len(rows) > 0 {
+			ui.pendingRemove = rows
+			ui.mode = modeConfirmRemove
+			ui.message = fmt.Sprintf("Remove %d addons? (y/n)", len(rows))
+		}
+	case 'p':
+		ui.togglePin(ui.idle(ui.targets()))
+	case 'c':
+		ui.cycleChannel(ui.idle(ui.targets()))
+	case 'r':
+		for _, row := range ui.targets() {
+			ui.check(row)
+		}
+	case '/', 's':
+		ui.mode = modeSearchInput
+		ui.query = ""
+	}
+}
+
+func (ui *tui) handleSearchInputKey(k key) {
+	switch k.code {
+	case keyEscape:
+		ui.mode = modeAddons
+	case keyEnter:
+		ui.mode = modeAddons
+		if query := strings.TrimSpace(ui.query); query != "" && !ui.searching {
+			ui.search(query)
+		}
+	case keyBackspace:
+		if r := []rune(ui.query); len(r) > 0 {
+			ui.query = string(r[:len(r)-1])
+		}
+	case keyRune:
+		ui.query += string(k.r)
+	}
+}
+
+func (ui *tui) handleResultsKey(k key) {
+	_, height := ui.term.size()
+	page := listHeight(height)
+
+	switch k.code {
+	case keyUp, keyDown, keyPageUp, keyPageDown, keyHome, keyEnd:
+		ui.resultCursor = moveCursor(ui.resultCursor, len(ui.results), page, k.code)
+	case keyEscape:
+		ui.mode = modeAddons
+	case keyEnter:
+		if ui.resultCursor < len(ui.results) {
+			ui.install(ui.results[ui.resultCursor])
+			ui.mode = modeAddons
+			ui.cursor = len(ui.rows) - 1
+		}
+	case keyRune:
+		switch k.r {
+		case 'q':
+			ui.mode = modeAddons
+		case 'j':
+			ui.resultCursor = moveCursor(ui.resultCursor, len(ui.results), page, keyDown)
+		case 'k':
+			ui.resultCursor = moveCursor(ui.resultCursor, len(ui.results), page, keyUp)
+		case '/', 's':
+			ui.mode = modeSearchInput
+		}
+	}
+}
+
+// moveCursor returns the new position of a cursor in a list of the given length after the key is pressed.
+func moveCursor(cursor int, length int, page int, code keyCode) int {
+	switch code {
+	case keyUp:
+		cursor--
+	case keyDown:
+		cursor++
+	case keyPageUp:
+		cursor -= page
+	case keyPageDown:
+		cursor += page
+	case keyHome:
+		cursor = 0
+	case keyEnd:
+		cursor = length - 1
+	}
+
+	if cursor >= length {
+		cursor = length - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	return cursor
+}
+
+// scroll returns the first visible line of a list so that the cursor is on screen.
+func scroll(offset int, cursor int, page int) int {
+	if cursor < offset {
+		return cursor
+	}
+	if cursor >= offset+page {
+		return cursor - page + 1
+	}
+	return offset
+}
+
+// listHeight returns the number of list entries that fit on the screen, leaving room for the title, column headers,
+// message line and help line.
+func listHeight(height int) int {
+	if height < 5 {
+		return 1
+	}
+	return height - 4
+}
+
+func (ui *tui) render() {
+	width, height := ui.term.size()
+	page := listHeight(height)
+
+	ui.term.clear()
+	if ui.mode == modeResults {
+		ui.renderResults(width, page)
+	} else {
+		ui.renderAddons(width, page)
+	}
+
+	for len(ui.term.lines) < height-2 {
+		ui.term.print(width, styleNormal, "")
+	}
+
+	switch {
+	case ui.mode == modeSearchInput:
+		ui.term.print(width, styleNormal, fmt.Sprintf("Search: %s█", ui.query))
+	default:
+		ui.term.print(width, styleBold, ui.message)
+	}
+
+	switch ui.mode {
+	case modeResults:
+		ui.term.print(width, styleDim, "↑↓ move  enter install  / new search  esc back")
+	case modeSearchInput:
+		ui.term.print(width, styleDim, "enter search  esc cancel")
+	default:
+		ui.term.print(width, styleDim, tuiHelp)
+	}
+	ui.term.flush(height)
+}
+
+func (ui *tui) renderAddons(width int, page int) {
+	outdated, selected := 0, 0
+	for _, row := range ui.rows {
+		if row.outdated {
+			outdated++
+		}
+		if row.selected {
+			selected++
+		}
+	}
+
+	title := fmt.Sprintf(" wadman: %d addons, %d with updates", len(ui.rows), outdated)
+	if selected > 0 {
+		title += fmt.Sprintf(", %d selected", selected)
+	}
+	if ui.active > 0 {
+		title += fmt.Sprintf(", %d jobs running", ui.active)
+	}
+	ui.term.print(width, styleReverse, title)
+
+	nameWidth := width * 35 / 100
+	versionWidth := width * 20 / 100
+	statusWidth := width * 25 / 100
+	settingsWidth := width - nameWidth - versionWidth - statusWidth - 4
+	ui.term.print(width, styleBold, "    "+column("Name", nameWidth)+column("Version", versionWidth)+column("Status", statusWidth)+column("Settings", settingsWidth))
+
+	ui.offset = scroll(ui.offset, ui.cursor, page)
+	for i := ui.offset; i < len(ui.rows) && i < ui.offset+page; i++ {
+		row := ui.rows[i]
+		mark := "[ ] "
+		if row.selected {
+			mark = "[x] "
+		}
+
+		style := styleNormal
+		if i == ui.cursor {
+			style = styleReverse
+		}
+		ui.term.print(width, style, mark+column(row.name, nameWidth)+column(row.version, versionWidth)+column(row.status, statusWidth)+column(row.settings, settingsWidth))
+	}
+
+	if len(ui.rows) == 0 {
+		ui.term.print(width, styleNormal, "    No addons installed. Press / to search for some.")
+	}
+}
+
+func (ui *tui) renderResults(width int, page int) {
+	ui.term.print(width, styleReverse, fmt.Sprintf(" Search results: %d addons", len(ui.results)))
+
+	nameWidth := width * 30 / 100
+	sourceWidth := 15
+	downloadsWidth := 12
+	summaryWidth := width - nameWidth - sourceWidth - downloadsWidth - 2
+	ui.term.print(width, styleBold, "  "+column("Name", nameWidth)+column("Source", sourceWidth)+column("Downloads", downloadsWidth)+column("Summary", summaryWidth))
+
+	installed := make(map[string]bool)
+	for _, row := range ui.rows {
+		installed[row.id] = true
+	}
+
+	ui.resultOffset = scroll(ui.resultOffset, ui.resultCursor, page)
+	for i := ui.resultOffset; i < len(ui.results) && i < ui.resultOffset+page; i++ {
+		result := ui.results[i]
+		mark := "  "
+		if installed[result.Id] {
+			mark = "✓ "
+		}
+
+		style := styleNormal
+		if i == ui.resultCursor {
+			style = styleReverse
+		}
+		ui.term.print(width, style, mark+column(result.Name, nameWidth)+column(result.Source, sourceWidth)+column(fmt.Sprintf("%d", result.Downloads), downloadsWidth)+column(result.Summary, summaryWidth))
+	}
+}
@@ -2,23 +2,31 @@ package main
 
 import (
 	"fmt"
+	"github.com/csmith/wadman"
 	"github.com/spf13/cobra"
-	"io"
-	"io/ioutil"
 	"os"
-	"strconv"
-	"strings"
 )
 
 func init() {
 	rootCommand.AddCommand(updateCommand)
 	updateCommand.Flags().BoolVarP(&force, "force", "f", false, "Replace all addons with the latest version")
 	updateCommand.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show debug information when checking for updates")
+	updateCommand.Flags().BoolVarP(&showChangelogs, "changelog", "c", false, "Show the changelog for each updated addon")
 }
 
 var force bool
 var verbose bool
 
+type updateRecord struct {
+	Id         string `json:"id" yaml:"id"`
+	Name       string `json:"name" yaml:"name"`
+	OldVersion string `json:"old_version" yaml:"old_version"`
+	NewVersion string `json:"new_version" yaml:"new_version"`
+	Updated    bool   `json:"updated" yaml:"updated"`
+	Changelog  string `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
 var updateCommand = &cobra.Command{
 	Use:   "update [id [id ...]]",
 	Short: "Update installed addons",
@@ -26,47 +34,78 @@ var updateCommand = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		defer saveConfig()
 
-		matched := 0
-		filtered := len(args) > 0
-		included := toIdMap(args)
+		observer := verboseObserver()
 
-		var debug io.Writer
-		if verbose {
-			debug = os.Stdout
-		} else {
-			debug = ioutil.Discard
-		}
+		records := []updateRecord{}
+		for _, addon := range selectAddons(args) {
+			if interrupted() {
+				break
+			}
 
-		for i := range config.Addons {
-			addon := config.Addons[i]
-			if !filtered || included[addon.ShortName()] {
-				matched++
-				updated, err := addon.Update(install, debug, force)
-				if err != nil {
-					fmt.Printf("Unable to update addon '%s': %v\n", addon.DisplayName(), err)
-				} else if force {
-					fmt.Printf("Reinstalled addon '%s' at version %s\n", addon.DisplayName(), addon.CurrentVersion())
-				} else if updated {
-					fmt.Printf("Updated addon '%s' to version %s\n", addon.DisplayName(), addon.CurrentVersion())
-				}
+			record, err := updateAddon(addon, observer, force, showChangelogs)
+			records = append(records, record)
+
+			if err != nil {
+				addonFailed()
+				logf("Unable to update addon '%s': %v\n", addon.DisplayName(), err)
+			} else if force {
+				logf("Reinstalled addon '%s' at version %s\n", addon.DisplayName(), addon.CurrentVersion())
+			} else if record.Updated {
+				logf("Updated addon '%s' to version %s\n", addon.DisplayName(), addon.CurrentVersion())
+			}
+
+			if record.Changelog != "" && !structuredOutput() {
+				printChangelog(os.Stdout, record.Name, record.NewVersion, record.Changelog)
 			}
 		}
 
-		if len(config.Addons) == 0 {
+		notifyWebhooks(records, logf)
+
+		if structuredOutput() {
+			writeOutput(records)
+		} else if len(config.Addons) == 0 {
 			fmt.Printf("No addons configured. Use the 'add' command to add new addons.\n")
 		} else {
-			fmt.Printf("Finished checking %d addons\n", matched)
+			fmt.Printf("Finished checking %d addons\n", len(records))
 		}
 	},
 }
 
+// updateAddon updates (or, if force is true, reinstalls) the addon. If changelog is true and a new version was
+// installed, its changelog is included in the record.
+func updateAddon(addon wadman.Addon, observer wadman.Observer, force bool, changelog bool) (updateRecord, error) {
+	record := updateRecord{Id: addon.ShortName(), OldVersion: addon.CurrentVersion()}
+	updated, err := wadman.Update(ctx, addon, install, observer, force)
+	record.Name = addon.DisplayName()
+	record.NewVersion = addon.CurrentVersion()
+	record.Updated = updated
+	record.Error = errorString(err)
+	if changelog && updated && record.OldVersion != record.NewVersion {
+		record.Changelog = fetchChangelog(addon, addon.Locked().Release())
+	}
+	return record, err
+}
+
+// selectAddons returns the configured addons with the given IDs, or all addons if no IDs are given.
+func selectAddons(ids []string) []wadman.Addon {
+	if len(ids) == 0 {
+		return config.Addons
+	}
+
+	included := toIdMap(ids)
+	var res []wadman.Addon
+	for _, addon := range config.Addons {
+		if included[addon.ShortName()] {
+			res = append(res, addon)
+		}
+	}
+	return res
+}
+
 func toIdMap(args []string) map[string]bool {
 	res := make(map[string]bool)
 	for _, a := range args {
-		if i, err := strconv.Atoi(a); err == nil {
-			res[fmt.Sprintf("curse:%d", i)] = true
-		}
-		res[strings.ToLower(a)] = true
+		res[normaliseId(a)] = true
 	}
 	return res
 }
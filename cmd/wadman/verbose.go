@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/csmith/wadman/wow"
+	"strings"
+)
+
+// verboseObserver returns an observer that renders progress events as debugging information if the user has asked
+// for verbose output, or nil otherwise.
+func verboseObserver() wadman.Observer {
+	if !verbose {
+		return nil
+	}
+
+	out := messages()
+	deciles := make(map[wadman.Addon]int64)
+	return func(event wadman.Event) {
+		switch e := event.(type) {
+		case wadman.CheckingEvent:
+			fmt.Fprintf(out, "\n================================================================================\n")
+			fmt.Fprintf(out, "Checking for updates to addon %s (%s)\n\n", e.Addon.ShortName(), e.Addon.DisplayName())
+
+		case wadman.CandidateEvent:
+			fmt.Fprintf(out, "Found file %s (%s)\n", e.FileId, e.Version)
+			if e.Flavour != wow.FlavourUnknown {
+				fmt.Fprintf(out, "\tFlavour: %s (valid: %t)\n", e.Flavour, e.FlavourValid)
+			}
+			if e.Channel != wadman.ChannelDefault {
+				fmt.Fprintf(out, "\tChannel: %s (valid: %t)\n", e.Channel, e.ChannelValid)
+			}
+			if e.Alternate {
+				fmt.Fprintf(out, "\tAlternative file (valid: false)\n")
+			}
+			if len(e.GameVersions) > 0 {
+				fmt.Fprintf(out, "\tGame versions: %s (valid: %t)\n", strings.Join(e.GameVersions, ", "), e.GameVersionsValid)
+			}
+			if !e.Date.IsZero() {
+				fmt.Fprintf(out, "\tTime: %s\n", e.Date)
+			}
+			if e.Selected {
+				fmt.Fprintf(out, "\t<< SELECTED\n")
+			} else if e.Eligible {
+				fmt.Fprintf(out, "\t<< SKIPPED\n")
+			}
+			fmt.Fprintln(out)
+
+		case wadman.ResolvedEvent:
+			if e.Newer {
+				fmt.Fprintf(out, "Update found for '%s'. Installed file ID: %s, latest file ID: %s (version: %s)\n", e.Addon.DisplayName(), e.Addon.Locked().FileId, e.Release.FileId, e.Release.Version)
+			} else {
+				fmt.Fprintf(out, "No update found for '%s'. Installed file ID: %s, latest file ID: %s (version: %s)\n", e.Addon.DisplayName(), e.Addon.Locked().FileId, e.Release.FileId, e.Release.Version)
+			}
+
+		case wadman.DownloadProgressEvent:
+			if e.Total <= 0 {
+				return
+			}
+			if decile := e.Bytes * 10 / e.Total; decile > deciles[e.Addon] || e.Bytes == e.Total {
+				deciles[e.Addon] = decile
+				fmt.Fprintf(out, "\tDownloaded %d%% (%d of %d bytes)\n", decile*10, e.Bytes, e.Total)
+			}
+
+		case wadman.ExtractedEvent:
+			fmt.Fprintf(out, "Extracted directories: %s\n", strings.Join(e.Directories, ", "))
+
+		case wadman.DoneEvent:
+			if e.Pinned {
+				fmt.Fprintf(out, "Addon %s is pinned to file %s\n", e.Addon.ShortName(), e.Addon.Pin())
+			}
+		}
+	}
+}
@@ -1,7 +1,14 @@
 package main
 
+import "os"
+
 func main() {
 	if err := rootCommand.Execute(); err != nil {
 		bail("Error executing command: %v", err)
 	}
+
+	if ctx != nil && ctx.Err() != nil {
+		exitCode = exitInterrupted
+	}
+	os.Exit(exitCode)
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"github.com/csmith/wadman"
+	"github.com/csmith/wadman/webhook"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+func init() {
+	rootCommand.AddCommand(webhookCommand)
+	webhookCommand.AddCommand(webhookAddCommand, webhookRemoveCommand, webhookListCommand, webhookTestCommand)
+	webhookAddCommand.Flags().StringVar(&webhookFormat, "format", string(webhook.FormatJson), "Payload format: json, discord or slack")
+	webhookAddCommand.Flags().StringVar(&webhookTemplate, "template", "", "Go template used to render the message (default: a list of updates and failures)")
+	webhookAddCommand.Flags().StringVar(&webhookTemplateFile, "template-file", "", "File to read the message template from")
+}
+
+var (
+	webhookFormat       string
+	webhookTemplate     string
+	webhookTemplateFile string
+)
+
+var webhookCommand = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage webhooks that are notified after addons are updated",
+}
+
+var webhookAddCommand = &cobra.Command{
+	Use:   "add <url>",
+	Short: "Add a webhook",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := webhook.ParseFormat(webhookFormat)
+		if err != nil {
+			bail("%v", err)
+		}
+
+		template := webhookTemplate
+		if webhookTemplateFile != "" {
+			b, err := ioutil.ReadFile(webhookTemplateFile)
+			if err != nil {
+				bail("Unable to read template: %v", err)
+			}
+			template = string(b)
+		}
+		if _, err := webhook.ParseTemplate(template); err != nil {
+			bail("Invalid template: %v", err)
+		}
+
+		for _, target := range config.Webhooks {
+			if target.Url == args[0] {
+				bail("Webhook %s already exists", args[0])
+			}
+		}
+
+		config.Webhooks = append(config.Webhooks, webhook.Target{Url: args[0], Format: format, Template: template})
+		saveConfig()
+		fmt.Printf("Added %s webhook %s\n", format, args[0])
+	},
+}
+
+var webhookRemoveCommand = &cobra.Command{
+	Use:   "remove <url>",
+	Short: "Remove a webhook",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var remaining []webhook.Target
+		for _, target := range config.Webhooks {
+			if target.Url != args[0] {
+				remaining = append(remaining, target)
+			}
+		}
+
+		if len(remaining) == len(config.Webhooks) {
+			fmt.Printf("No matching webhooks found\n")
+			return
+		}
+
+		config.Webhooks = remaining
+		saveConfig()
+		fmt.Printf("Removed webhook %s\n", args[0])
+	},
+}
+
+var webhookListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List webhooks",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if structuredOutput() {
+			writeOutput(config.Webhooks)
+			return
+		}
+
+		if len(config.Webhooks) == 0 {
+			fmt.Printf("No webhooks configured. Use 'wadman webhook add' to add one.\n")
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"URL", "Format", "Template"})
+		table.SetAutoWrapText(false)
+		for _, target := range config.Webhooks {
+			template := "default"
+			if target.Template != "" {
+				template = "custom"
+			}
+			table.Append([]string{target.Url, string(target.Format), template})
+		}
+		table.Render()
+	},
+}
+
+var webhookTestCommand = &cobra.Command{
+	Use:   "test [url]",
+	Short: "Send an example notification to each webhook, or the given one",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		summary := newSummary()
+		summary.Updated = append(summary.Updated, webhook.Update{Id: "curse:3358", Name: "Example Addon", OldVersion: "v1.0.0", NewVersion: "v1.1.0"})
+		summary.Failed = append(summary.Failed, webhook.Failure{Id: "wowi:1234", Name: "Another Addon", Error: "example error"})
+
+		queue := &webhook.Queue{}
+		sent := 0
+		for _, target := range config.Webhooks {
+			if len(args) > 0 && target.Url != args[0] {
+				continue
+			}
+
+			sent++
+			body, err := target.Payload(summary)
+			if err == nil {
+				err = queue.Send(ctx, target.Url, body)
+			}
+			if err != nil {
+				addonFailed()
+				fmt.Printf("Unable to notify %s: %v\n", target.Url, err)
+			} else {
+				fmt.Printf("Notified %s\n", target.Url)
+			}
+		}
+
+		if sent == 0 {
+			fmt.Printf("No matching webhooks found\n")
+		}
+	},
+}
+
+// newSummary creates an empty summary of updates made on this machine.
+func newSummary() *webhook.Summary {
+	host, _ := os.Hostname()
+	return &webhook.Summary{Time: time.Now(), Host: host, Updated: []webhook.Update{}, Failed: []webhook.Failure{}}
+}
+
+// notifyWebhooks queues a summary of the given update results for each configured webhook, then tries to deliver it
+// along with any earlier notifications that couldn't be sent. Nothing is queued if no addons were updated and none
+// failed. Problems are reported using warn, as they shouldn't stop addons from being updated.
+func notifyWebhooks(records []updateRecord, warn func(format string, args ...interface{})) {
+	summary := newSummary()
+	for _, record := range records {
+		if record.Error != "" {
+			summary.Failed = append(summary.Failed, webhook.Failure{Id: record.Id, Name: record.Name, Error: record.Error})
+		} else if record.Updated {
+			summary.Updated = append(summary.Updated, webhook.Update{Id: record.Id, Name: record.Name, OldVersion: record.OldVersion, NewVersion: record.NewVersion})
+		}
+	}
+
+	path, err := wadman.WebhookQueuePath()
+	if err != nil {
+		warn("Unable to build webhook queue path: %v\n", err)
+		return
+	}
+
+	queue, err := webhook.LoadQueue(path)
+	if err != nil {
+		warn("Unable to load webhook queue: %v\n", err)
+		return
+	}
+
+	if !summary.Empty() {
+		for _, target := range config.Webhooks {
+			if err := queue.Add(target, summary); err != nil {
+				warn("%v\n", err)
+			}
+		}
+	}
+
+	if len(queue.Deliveries) == 0 {
+		return
+	}
+
+	_, errs := queue.Flush(ctx)
+	for _, err := range errs {
+		warn("Webhook %v\n", err)
+	}
+
+	if err := queue.Save(); err != nil {
+		warn("Unable to save webhook queue: %v\n", err)
+	}
+}
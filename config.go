@@ -3,19 +3,36 @@ package wadman
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/csmith/wadman/webhook"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // configVersion specifies the maximum version of the config file this build of wadman supports
 // version 1 was the original config format
 // version 2 changed the install_path field to the base _retail_ directory instead of the addons directory
 // version 3 added a type field to addons
+// Older files are upgraded by the migrations registered in migrations.go.
 const configVersion = 3
 
 type Config struct {
 	InstallPath string
 	Addons      []Addon
+	// WagoApiKey is used to access the Wago Addons API.
+	WagoApiKey string
+	// WagoIoApiKey is used to check private WeakAuras and Plater imports on Wago.io for updates.
+	WagoIoApiKey string
+	// UpdateInterval is how often the daemon checks for updates. Zero means the default should be used.
+	UpdateInterval time.Duration
+	// UpdateJitter is the maximum amount of time the daemon randomly adds to or subtracts from the interval, so
+	// that checks don't happen at exactly the same time every day.
+	UpdateJitter time.Duration
+	// ApiToken must be supplied by clients of the local HTTP API.
+	ApiToken string
+	// Webhooks are notified with a summary after addons are updated.
+	Webhooks []webhook.Target
 }
 
 func ConfigPath() (string, error) {
@@ -27,8 +44,28 @@ func ConfigPath() (string, error) {
 	return filepath.Join(basePath, "wadman", "config.json"), nil
 }
 
+// CachePath returns the directory wadman uses to cache data that can be re-downloaded, such as git repositories.
+func CachePath() (string, error) {
+	basePath, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(basePath, "wadman"), nil
+}
+
+// WebhookQueuePath returns the file that webhook notifications are kept in until they have been delivered.
+func WebhookQueuePath() (string, error) {
+	basePath, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(basePath, "wadman", "webhook-queue.json"), nil
+}
+
 func LoadConfig(path string) (*Config, error) {
-	f, err := os.Open(path)
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &Config{}, nil
@@ -36,72 +73,121 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	defer f.Close()
+	_, b, _, err = MigrateConfigData(b)
+	if err != nil {
+		return nil, err
+	}
 
 	data := &struct {
-		InstallPath string            `json:"install_path"`
-		Version     int               `json:"version"`
-		Addons      []json.RawMessage `json:"addons"`
+		InstallPath    string            `json:"install_path"`
+		Version        int               `json:"version"`
+		Addons         []json.RawMessage `json:"addons"`
+		WagoApiKey     string            `json:"wago_api_key,omitempty"`
+		WagoIoApiKey   string            `json:"wago_io_api_key,omitempty"`
+		UpdateInterval string            `json:"update_interval,omitempty"`
+		UpdateJitter   string            `json:"update_jitter,omitempty"`
+		ApiToken       string            `json:"api_token,omitempty"`
+		Webhooks       []webhook.Target  `json:"webhooks,omitempty"`
 	}{}
-	err = json.NewDecoder(f).Decode(data)
+	err = json.Unmarshal(b, data)
 	if err != nil {
 		return nil, err
 	}
 
-	if data.Version > configVersion {
-		return nil, fmt.Errorf("config file version %d requires a new version of wadman", data.Version)
+	var interval, jitter time.Duration
+	if data.UpdateInterval != "" {
+		if interval, err = time.ParseDuration(data.UpdateInterval); err != nil {
+			return nil, fmt.Errorf("invalid update interval: %v", err)
+		}
 	}
-
-	if data.Version < 2 {
-		// Config version 2 uses the base directory for the install path, instead of the addons directory
-		data.InstallPath = filepath.Dir(filepath.Dir(data.InstallPath))
+	if data.UpdateJitter != "" {
+		if jitter, err = time.ParseDuration(data.UpdateJitter); err != nil {
+			return nil, fmt.Errorf("invalid update jitter: %v", err)
+		}
 	}
 
 	var addons []Addon
 	for i := range data.Addons {
-		base := BaseAddon{}
-		if err := json.Unmarshal(data.Addons[i], &base); err != nil {
-			return nil, err
-		}
-
-		inst, err := base.Type.NewInstance()
+		inst, err := unmarshalAddon(data.Addons[i])
 		if err != nil {
 			return nil, err
 		}
 
-		if err := json.Unmarshal(data.Addons[i], &inst); err != nil {
-			return nil, err
-		}
-
 		addons = append(addons, inst)
 	}
 
 	return &Config{
-		InstallPath: data.InstallPath,
-		Addons:      addons,
+		InstallPath:    data.InstallPath,
+		Addons:         addons,
+		WagoApiKey:     data.WagoApiKey,
+		WagoIoApiKey:   data.WagoIoApiKey,
+		UpdateInterval: interval,
+		UpdateJitter:   jitter,
+		ApiToken:       data.ApiToken,
+		Webhooks:       data.Webhooks,
 	}, nil
 }
 
 func SaveConfig(path string, config *Config) error {
+	_, err := UpgradeConfig(path, config)
+	return err
+}
+
+// UpgradeConfig saves the config in the same way as SaveConfig, backing up the existing file first if it was written
+// by an older version of wadman. The path of the backup is returned, or an empty string if none was made.
+func UpgradeConfig(path string, config *Config) (string, error) {
 	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
-		return err
+		return "", err
 	}
 
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+	var addons []json.RawMessage
+	for i := range config.Addons {
+		raw, err := marshalAddon(config.Addons[i])
+		if err != nil {
+			return "", err
+		}
+		addons = append(addons, raw)
 	}
 
 	data := &struct {
-		InstallPath string  `json:"install_path"`
-		Version     int     `json:"version"`
-		Addons      []Addon `json:"addons"`
+		InstallPath    string            `json:"install_path"`
+		Version        int               `json:"version"`
+		Addons         []json.RawMessage `json:"addons"`
+		WagoApiKey     string            `json:"wago_api_key,omitempty"`
+		WagoIoApiKey   string            `json:"wago_io_api_key,omitempty"`
+		UpdateInterval string            `json:"update_interval,omitempty"`
+		UpdateJitter   string            `json:"update_jitter,omitempty"`
+		ApiToken       string            `json:"api_token,omitempty"`
+		Webhooks       []webhook.Target  `json:"webhooks,omitempty"`
 	}{
 		config.InstallPath,
 		configVersion,
-		config.Addons,
+		addons,
+		config.WagoApiKey,
+		config.WagoIoApiKey,
+		durationString(config.UpdateInterval),
+		durationString(config.UpdateJitter),
+		config.ApiToken,
+		config.Webhooks,
+	}
+
+	backup, err := BackupConfig(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to back up old config file: %v", err)
 	}
 
+	return backup, writeJson(path, data)
+}
+
+// writeJson saves the data as indented JSON. It is written to a temporary file which then replaces the original, so
+// the file is never left partially written if wadman is interrupted or the disk is full.
+func writeJson(path string, data interface{}) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s-*", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
 	enc := json.NewEncoder(f)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(data); err != nil {
@@ -109,5 +195,35 @@ func SaveConfig(path string, config *Config) error {
 		return err
 	}
 
-	return f.Close()
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), privateMode(path)); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// privateMode returns the permissions to write the given file with. The config contains API keys and tokens, so files
+// are only readable by their owner, unless the existing file has been restricted further.
+func privateMode(path string) os.FileMode {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode &= info.Mode().Perm()
+	}
+	return mode
+}
+
+// durationString formats a duration for saving in the config file, using an empty string for zero.
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
 }
@@ -0,0 +1,48 @@
+package wadman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSaveConfigMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes aren't supported on windows")
+	}
+
+	tests := []struct {
+		name     string
+		existing os.FileMode
+		want     os.FileMode
+	}{
+		{name: "new file", want: 0600},
+		{name: "world readable", existing: 0644, want: 0600},
+		{name: "owner only", existing: 0600, want: 0600},
+		{name: "read only", existing: 0400, want: 0400},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if tt.existing != 0 {
+			if err := ioutil.WriteFile(path, readConfigFixture(t, 1), tt.existing); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := SaveConfig(path, &Config{ApiToken: "secret"}); err != nil {
+			t.Fatalf("%s: SaveConfig() failed: %v", tt.name, err)
+		}
+
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != tt.want {
+			t.Errorf("%s: config mode = %v, %v, want %v", tt.name, info.Mode().Perm(), err, tt.want)
+		}
+
+		if tt.existing != 0 {
+			if info, err := os.Stat(path + ".v1.bak"); err != nil || info.Mode().Perm() != tt.want {
+				t.Errorf("%s: backup mode = %v, %v, want %v", tt.name, info.Mode().Perm(), err, tt.want)
+			}
+		}
+	}
+}
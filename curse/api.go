@@ -1,9 +1,10 @@
 package curse
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"math"
 	"net/http"
 	"net/url"
@@ -15,8 +16,8 @@ type Type int
 
 const (
 	Release Type = 1
-	Beta         = 2
-	Alpha        = 3
+	Beta    Type = 2
+	Alpha   Type = 3
 )
 
 type AddonFile struct {
@@ -30,14 +31,33 @@ type AddonFile struct {
 	Versions    []string  `json:"gameVersion"`
 }
 
+type Author struct {
+	Name string `json:"name"`
+}
+
+type Category struct {
+	Name string `json:"name"`
+}
+
+type GameVersionFile struct {
+	Flavour string `json:"gameVersionFlavor"`
+}
+
 type AddonResponse struct {
-	Id    int         `json:"id"`
-	Name  string      `json:"name"`
-	Files []AddonFile `json:"latestFiles"`
+	Id               int               `json:"id"`
+	Name             string            `json:"name"`
+	Summary          string            `json:"summary"`
+	WebsiteUrl       string            `json:"websiteUrl"`
+	Authors          []Author          `json:"authors"`
+	Categories       []Category        `json:"categories"`
+	DownloadCount    float64           `json:"downloadCount"`
+	DateModified     time.Time         `json:"dateModified"`
+	Files            []AddonFile       `json:"latestFiles"`
+	GameVersionFiles []GameVersionFile `json:"gameVersionLatestFiles"`
 }
 
-func GetAddon(id int) (*AddonResponse, error) {
-	res, err := http.Get(fmt.Sprintf("https://addons-ecs.forgesvc.net/api/v2/addon/%d", id))
+func GetAddon(ctx context.Context, id int) (*AddonResponse, error) {
+	res, err := get(ctx, fmt.Sprintf("https://addons-ecs.forgesvc.net/api/v2/addon/%d", id))
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +69,38 @@ func GetAddon(id int) (*AddonResponse, error) {
 	return addon, err
 }
 
-func SearchAddons(query string) ([]*AddonResponse, error) {
-	res, err := http.Get(fmt.Sprintf("https://addons-ecs.forgesvc.net/api/v2/addon/search?gameId=1&searchFilter=%s", url.QueryEscape(query)))
+// GetFiles retrieves every file that has been published for the given addon, not just the latest ones.
+func GetFiles(ctx context.Context, id int) ([]AddonFile, error) {
+	res, err := get(ctx, fmt.Sprintf("https://addons-ecs.forgesvc.net/api/v2/addon/%d/files", id))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var files []AddonFile
+	err = json.NewDecoder(res.Body).Decode(&files)
+	return files, err
+}
+
+// GetChangelog retrieves the changelog for the given file of an addon. The changelog is usually formatted as HTML.
+func GetChangelog(ctx context.Context, id int, fileId int) (string, error) {
+	res, err := get(ctx, fmt.Sprintf("https://addons-ecs.forgesvc.net/api/v2/addon/%d/file/%d/changelog", id, fileId))
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to retrieve changelog: %s", res.Status)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	return string(b), err
+}
+
+func SearchAddons(ctx context.Context, query string) ([]*AddonResponse, error) {
+	res, err := get(ctx, fmt.Sprintf("https://addons-ecs.forgesvc.net/api/v2/addon/search?gameId=1&searchFilter=%s", url.QueryEscape(query)))
 	if err != nil {
 		return nil, err
 	}
@@ -62,53 +112,72 @@ func SearchAddons(query string) ([]*AddonResponse, error) {
 	return addons, err
 }
 
-func LatestFile(details *AddonResponse, debug io.Writer) *AddonFile {
-	var matches []AddonFile
-
-	for i := range details.Files {
-		f := details.Files[i]
-
-		fmt.Fprintf(debug,
-			"Found file %d (%s)\n"+
-				"\tFlavour: %s (valid: %t)\n"+
-				"\tType: %d (valid: %t)\n"+
-				"\tAlternative: %t (valid: %t)\n"+
-				"\n",
-			f.FileId,
-			f.DisplayName,
-			f.Flavour,
-			f.Flavour == "wow_retail",
-			f.Type,
-			f.Type <= Beta,
-			f.Alternate,
-			!f.Alternate,
-		)
-
-		if f.Flavour == "wow_retail" && f.Type <= Beta && !f.Alternate {
-			matches = append(matches, f)
-		}
+// get performs a HTTP GET request for the given URL, which will be aborted if the context is cancelled.
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Fprintf(debug, "Found %d potential versions:\n", len(matches))
+	return http.DefaultClient.Do(req)
+}
+
+// Candidate describes a file considered by LatestFile, and the checks that determined whether it was selected.
+type Candidate struct {
+	File *AddonFile
+	// FlavourValid indicates whether the file is for the requested flavour of the game.
+	FlavourValid bool
+	// TypeValid indicates whether the file's release type is at least as stable as requested.
+	TypeValid bool
+	// AlternateValid indicates whether the file is a primary (not alternate) file.
+	AlternateValid bool
+	// VersionValid indicates whether the file supports a recent game version.
+	VersionValid bool
+	// Selected indicates whether the file was chosen by LatestFile.
+	Selected bool
+}
+
+// Eligible determines whether the file passed the basic checks and was considered for selection.
+func (c Candidate) Eligible() bool {
+	return c.FlavourValid && c.TypeValid && c.AlternateValid
+}
+
+// LatestFile selects the most appropriate file to install from the addon's latest files. Only files for the given
+// flavour (e.g. "wow_classic") are used, and files less stable than maxType are ignored. If report is non-nil, it is
+// called with details of every file that was considered.
+func LatestFile(details *AddonResponse, flavour string, maxType Type, report func(Candidate)) *AddonFile {
+	candidates := make([]Candidate, len(details.Files))
 
 	var bestFile *AddonFile
 	bestAge := math.MaxFloat64
 	bestValid := false
-	for i := range matches {
-		f := matches[i]
+	for i := range details.Files {
+		f := &details.Files[i]
+		c := &candidates[i]
+		c.File = f
+		c.FlavourValid = flavour != "" && f.Flavour == flavour
+		c.TypeValid = f.Type <= maxType
+		c.AlternateValid = !f.Alternate
+		c.VersionValid = validVersion(f)
+
+		if !c.Eligible() {
+			continue
+		}
+
 		age := time.Now().Sub(f.Date).Seconds()
-		valid := validVersion(&f)
-		if (valid == bestValid && age < bestAge) || (!bestValid && valid) {
-			bestFile = &f
+		if (c.VersionValid == bestValid && age < bestAge) || (!bestValid && c.VersionValid) {
+			bestFile = f
 			bestAge = age
-			bestValid = valid
-			fmt.Fprintf(debug, "\t[%d] Time: %s; Versions: %s << Best so far\n", f.FileId, f.Date, strings.Join(f.Versions, ","))
-		} else {
-			fmt.Fprintf(debug, "\t[%d] Time: %s; Versions: %s << SKIPPED\n", f.FileId, f.Date, strings.Join(f.Versions, ","))
+			bestValid = c.VersionValid
 		}
 	}
 
-	fmt.Fprintln(debug)
+	if report != nil {
+		for i := range candidates {
+			candidates[i].Selected = candidates[i].File == bestFile
+			report(candidates[i])
+		}
+	}
 
 	return bestFile
 }
@@ -0,0 +1,51 @@
+package curse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatestFile(t *testing.T) {
+	now := time.Now()
+	details := &AddonResponse{Files: []AddonFile{
+		{FileId: 1, Flavour: "wow_retail", Type: Release, Date: now.Add(-48 * time.Hour), Versions: []string{"9.0.2"}},
+		{FileId: 2, Flavour: "wow_retail", Type: Beta, Date: now.Add(-24 * time.Hour), Versions: []string{"9.0.2"}},
+		{FileId: 3, Flavour: "wow_classic", Type: Release, Date: now.Add(-12 * time.Hour), Versions: []string{"1.13.6"}},
+		{FileId: 4, Flavour: "wow_classic", Type: Release, Date: now, Alternate: true, Versions: []string{"1.13.6"}},
+		{FileId: 5, Flavour: "wow_burning_crusade", Type: Alpha, Date: now, Versions: []string{"2.5.1"}},
+	}}
+
+	tests := []struct {
+		flavour string
+		maxType Type
+		want    int
+	}{
+		{flavour: "wow_retail", maxType: Release, want: 1},
+		{flavour: "wow_retail", maxType: Beta, want: 2},
+		{flavour: "wow_classic", maxType: Alpha, want: 3},
+		{flavour: "wow_burning_crusade", maxType: Beta},
+		{flavour: "wow_burning_crusade", maxType: Alpha, want: 5},
+		{flavour: "", maxType: Alpha},
+	}
+	for _, tt := range tests {
+		reported := 0
+		got := LatestFile(details, tt.flavour, tt.maxType, func(c Candidate) {
+			reported++
+			if c.FlavourValid != (tt.flavour != "" && c.File.Flavour == tt.flavour) {
+				t.Errorf("LatestFile(%q) reported FlavourValid = %t for a %s file", tt.flavour, c.FlavourValid, c.File.Flavour)
+			}
+		})
+
+		if reported != len(details.Files) {
+			t.Errorf("LatestFile(%q) reported %d candidates, want %d", tt.flavour, reported, len(details.Files))
+		}
+
+		if tt.want == 0 {
+			if got != nil {
+				t.Errorf("LatestFile(%q, %d) = %d, want nil", tt.flavour, tt.maxType, got.FileId)
+			}
+		} else if got == nil || got.FileId != tt.want {
+			t.Errorf("LatestFile(%q, %d) = %v, want file %d", tt.flavour, tt.maxType, got, tt.want)
+		}
+	}
+}
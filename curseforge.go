@@ -1,13 +1,19 @@
 package wadman
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/csmith/wadman/curse"
 	"github.com/csmith/wadman/wow"
-	"io"
-	"time"
+	"strconv"
+	"strings"
 )
 
+func init() {
+	RegisterProvider(&curseForgeProvider{})
+}
+
 type CurseForgeAddon struct {
 	BaseAddon
 	Id     int    `json:"id"`
@@ -16,7 +22,7 @@ type CurseForgeAddon struct {
 }
 
 func NewCurseForgeAddon(id int) Addon {
-	return &CurseForgeAddon{BaseAddon: BaseAddon{Type: TypeCurseForge}, Id: id}
+	return &CurseForgeAddon{BaseAddon: BaseAddon{AddonType: TypeCurseForge}, Id: id}
 }
 
 func (c *CurseForgeAddon) DisplayName() string {
@@ -27,49 +33,256 @@ func (c *CurseForgeAddon) ShortName() string {
 	return fmt.Sprintf("curse:%d", c.Id)
 }
 
-func (c *CurseForgeAddon) Update(w *wow.Install, debug io.Writer, force bool) (updated bool, err error) {
-	fmt.Fprintf(debug, "\n================================================================================\n")
-	fmt.Fprintf(debug, "Checking for updates to addon %d (%s)\n\n", c.Id, c.Name)
+func (c *CurseForgeAddon) Locked() LockEntry {
+	return c.LockEntry(c.ShortName(), c.Name, strconv.Itoa(c.FileId))
+}
+
+func (c *CurseForgeAddon) Install(ctx context.Context, w *wow.Install, release *Release, progress wow.ProgressFunc) error {
+	fileId, err := strconv.Atoi(release.FileId)
+	if err != nil {
+		return fmt.Errorf("invalid file ID for %s: %v", c.ShortName(), err)
+	}
+
+	if err := c.Deploy(ctx, w, release, progress); err != nil {
+		return err
+	}
+
+	c.FileId = fileId
+	if release.Name != "" {
+		c.Name = release.Name
+	}
+	return nil
+}
+
+type curseForgeProvider struct{}
+
+func (p *curseForgeProvider) Type() AddonType {
+	return TypeCurseForge
+}
+
+func (p *curseForgeProvider) Name() string {
+	return "CurseForge"
+}
+
+func (p *curseForgeProvider) NewAddon(id string) (Addon, error) {
+	target, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CurseForge project ID: %s", id)
+	}
+	return NewCurseForgeAddon(target), nil
+}
+
+func (p *curseForgeProvider) Marshal(addon Addon) ([]byte, error) {
+	return json.Marshal(addon)
+}
+
+func (p *curseForgeProvider) Unmarshal(data []byte) (Addon, error) {
+	addon := NewCurseForgeAddon(0).(*CurseForgeAddon)
+	if err := json.Unmarshal(data, addon); err != nil {
+		return nil, err
+	}
+
+	// Configs from before addons had types will have an empty type field
+	addon.AddonType = TypeCurseForge
+	return addon, nil
+}
+
+func (p *curseForgeProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	addons, err := curse.SearchAddons(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for i := range addons {
+		a := addons[i]
+		result := SearchResult{
+			Id:          fmt.Sprintf("curse:%d", a.Id),
+			Name:        a.Name,
+			Source:      p.Name(),
+			Summary:     a.Summary,
+			Downloads:   int64(a.DownloadCount),
+			LastUpdated: a.DateModified,
+		}
+
+		var authors []string
+		for _, author := range a.Authors {
+			authors = append(authors, author.Name)
+		}
+		result.Author = strings.Join(authors, ", ")
+
+		for _, c := range a.Categories {
+			result.Categories = append(result.Categories, c.Name)
+		}
+
+		seen := make(map[wow.Flavour]bool)
+		for _, f := range a.GameVersionFiles {
+			if flavour := curseFlavour(f.Flavour); flavour != wow.FlavourUnknown && !seen[flavour] {
+				seen[flavour] = true
+				result.Flavours = append(result.Flavours, flavour)
+			}
+		}
+
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (p *curseForgeProvider) Resolve(ctx context.Context, addon Addon, install *wow.Install, observer Observer) (*Release, error) {
+	c, ok := addon.(*CurseForgeAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a CurseForge addon", addon.ShortName())
+	}
 
-	details, err := curse.GetAddon(c.Id)
+	flavour := wow.FlavourRetail
+	if install != nil {
+		flavour = install.Flavour()
+	}
+
+	details, err := curse.GetAddon(ctx, c.Id)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	c.Name = details.Name
+	latest := curse.LatestFile(details, curseFlavourName(flavour), maxReleaseType(c.UpdateChannel), func(candidate curse.Candidate) {
+		f := candidate.File
+		observer.Notify(CandidateEvent{
+			Addon:    addon,
+			FileId:   strconv.Itoa(f.FileId),
+			Version:  f.DisplayName,
+			Date:     f.Date,
+			Eligible: candidate.Eligible(),
+			Selected: candidate.Selected,
 
-	latest := curse.LatestFile(details, debug)
+			Flavour:           curseFlavour(f.Flavour),
+			FlavourValid:      candidate.FlavourValid,
+			Channel:           curseChannel(f.Type),
+			ChannelValid:      candidate.TypeValid,
+			Alternate:         f.Alternate,
+			GameVersions:      f.Versions,
+			GameVersionsValid: candidate.VersionValid,
+		})
+	})
 	if latest == nil {
-		return false, fmt.Errorf("no releases found for addon %d (%s)", c.Id, c.Name)
+		return nil, fmt.Errorf("no %s releases found for addon %d (%s)", flavour, c.Id, details.Name)
+	}
+
+	return &Release{
+		FileId:  strconv.Itoa(latest.FileId),
+		Name:    details.Name,
+		Version: latest.DisplayName,
+		Url:     latest.Url,
+		Date:    latest.Date,
+	}, nil
+}
+
+func (p *curseForgeProvider) Details(ctx context.Context, addon Addon) (*AddonDetails, error) {
+	c, ok := addon.(*CurseForgeAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a CurseForge addon", addon.ShortName())
+	}
+
+	info, err := curse.GetAddon(ctx, c.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := curse.GetFiles(ctx, c.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &AddonDetails{
+		Name:        info.Name,
+		Description: info.Summary,
+		Website:     info.WebsiteUrl,
+	}
+
+	for _, author := range info.Authors {
+		details.Authors = append(details.Authors, author.Name)
 	}
 
-	if !force && c.FileId == latest.FileId {
-		fmt.Fprintf(
-			debug,
-			"No update found for '%s'. Installed file ID: %d, latest file ID: %d (version: %s)\n",
-			c.Name,
-			c.FileId,
-			latest.FileId,
-			latest.DisplayName,
-		)
-		return false, nil
+	for _, f := range files {
+		details.Files = append(details.Files, File{
+			Release: Release{
+				FileId:  strconv.Itoa(f.FileId),
+				Name:    info.Name,
+				Version: f.DisplayName,
+				Url:     f.Url,
+				Date:    f.Date,
+			},
+			Channel:      curseChannel(f.Type),
+			Flavour:      curseFlavour(f.Flavour),
+			GameVersions: f.Versions,
+		})
 	}
 
-	// Remove all the existing directories associated with the addon
-	if err := w.RemoveAddons(c.Directories); err != nil {
-		return false, err
+	return details, nil
+}
+
+func (p *curseForgeProvider) Changelog(ctx context.Context, addon Addon, release *Release) (string, error) {
+	c, ok := addon.(*CurseForgeAddon)
+	if !ok {
+		return "", fmt.Errorf("addon %s is not a CurseForge addon", addon.ShortName())
 	}
 
-	// Deploy the new version
-	dirs, err := w.InstallAddonFromUrl(latest.Url)
+	fileId, err := strconv.Atoi(release.FileId)
 	if err != nil {
-		return false, err
+		return "", fmt.Errorf("invalid file ID for %s: %v", c.ShortName(), err)
 	}
 
-	// Update our metadata
-	c.FileId = latest.FileId
-	c.Version = latest.DisplayName
-	c.LastUpdate = time.Now()
-	c.Directories = dirs
-	return true, nil
+	return curse.GetChangelog(ctx, c.Id, fileId)
+}
+
+// curseChannel converts a CurseForge release type into a Channel.
+func curseChannel(t curse.Type) Channel {
+	switch t {
+	case curse.Release:
+		return ChannelRelease
+	case curse.Beta:
+		return ChannelBeta
+	default:
+		return ChannelAlpha
+	}
+}
+
+// curseFlavour converts a CurseForge game version flavour into a wow.Flavour.
+func curseFlavour(flavour string) wow.Flavour {
+	switch flavour {
+	case "wow_retail":
+		return wow.FlavourRetail
+	case "wow_classic":
+		return wow.FlavourClassic
+	case "wow_burning_crusade":
+		return wow.FlavourBurningCrusade
+	default:
+		return wow.FlavourUnknown
+	}
+}
+
+// curseFlavourName returns the name CurseForge uses for the given flavour, or an empty string if it doesn't
+// distinguish files for that flavour.
+func curseFlavourName(flavour wow.Flavour) string {
+	switch flavour {
+	case wow.FlavourRetail:
+		return "wow_retail"
+	case wow.FlavourClassic:
+		return "wow_classic"
+	case wow.FlavourBurningCrusade:
+		return "wow_burning_crusade"
+	default:
+		return ""
+	}
+}
+
+// maxReleaseType returns the least stable type of file that may be installed for the given channel.
+func maxReleaseType(channel Channel) curse.Type {
+	switch channel {
+	case ChannelRelease:
+		return curse.Release
+	case ChannelAlpha:
+		return curse.Alpha
+	default:
+		return curse.Beta
+	}
 }
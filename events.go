@@ -0,0 +1,103 @@
+package wadman
+
+import (
+	"github.com/csmith/wadman/wow"
+	"time"
+)
+
+// Observer receives events describing the progress of operations on addons. A nil Observer discards all events.
+type Observer func(event Event)
+
+// Notify sends the event to the observer, if there is one.
+func (o Observer) Notify(event Event) {
+	if o != nil {
+		o(event)
+	}
+}
+
+// Event is a notification about the progress of an operation on an addon.
+type Event interface {
+	// Subject returns the addon the event relates to.
+	Subject() Addon
+}
+
+// CheckingEvent is sent when wadman starts checking for updates to an addon.
+type CheckingEvent struct {
+	Addon Addon
+}
+
+// CandidateEvent is sent for each file a provider considers when deciding which release to install.
+type CandidateEvent struct {
+	Addon   Addon
+	FileId  string
+	Version string
+	Date    time.Time
+	// Eligible indicates whether the file could be installed for the addon's flavour and channel.
+	Eligible bool
+	// Selected indicates whether this is the file the provider chose to install.
+	Selected bool
+
+	// The remaining fields describe the checks the file passed or failed during selection. Checks that the source
+	// doesn't perform are left empty and reported as valid.
+
+	// Flavour is the flavour of the game the file is for, and FlavourValid whether it matches the install.
+	Flavour      wow.Flavour
+	FlavourValid bool
+	// Channel is the channel the file was released on, and ChannelValid whether the addon's channel accepts it.
+	Channel      Channel
+	ChannelValid bool
+	// Alternate indicates the file is an alternative version of the addon, such as one without libraries, which is
+	// never installed.
+	Alternate bool
+	// GameVersions are the game versions the file supports, and GameVersionsValid whether any are recent. Files
+	// without a recent game version are only selected if there are no others.
+	GameVersions      []string
+	GameVersionsValid bool
+}
+
+// ResolvedEvent is sent once the latest release of an addon has been determined.
+type ResolvedEvent struct {
+	Addon   Addon
+	Release *Release
+	// Newer indicates whether the release differs from the currently installed file.
+	Newer bool
+}
+
+// DownloadProgressEvent is sent periodically while a release is being downloaded.
+type DownloadProgressEvent struct {
+	Addon Addon
+	Url   string
+	// Bytes is the number of bytes downloaded so far.
+	Bytes int64
+	// Total is the size of the download, or -1 if it is not known.
+	Total int64
+}
+
+// ExtractedEvent is sent once a release has been extracted into the addons directory.
+type ExtractedEvent struct {
+	Addon       Addon
+	Directories []string
+}
+
+// DoneEvent is sent when wadman has finished processing an addon successfully.
+type DoneEvent struct {
+	Addon Addon
+	// Updated indicates whether a new release was installed.
+	Updated bool
+	// Pinned indicates that the addon was not checked for updates because it is pinned to a specific file.
+	Pinned bool
+}
+
+// FailedEvent is sent when an error prevents wadman from processing an addon.
+type FailedEvent struct {
+	Addon Addon
+	Err   error
+}
+
+func (e CheckingEvent) Subject() Addon         { return e.Addon }
+func (e CandidateEvent) Subject() Addon        { return e.Addon }
+func (e ResolvedEvent) Subject() Addon         { return e.Addon }
+func (e DownloadProgressEvent) Subject() Addon { return e.Addon }
+func (e ExtractedEvent) Subject() Addon        { return e.Addon }
+func (e DoneEvent) Subject() Addon             { return e.Addon }
+func (e FailedEvent) Subject() Addon           { return e.Addon }
@@ -0,0 +1,63 @@
+package filelock
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrLocked is returned by TryLock if another process holds the lock.
+var ErrLocked = errors.New("locked by another process")
+
+// Lock is an advisory lock on a file, held until Unlock is called or the process exits.
+type Lock struct {
+	file *os.File
+}
+
+// TryLock takes an exclusive lock on the file at the given path, creating it if necessary. If another process holds
+// the lock, ErrLocked is returned immediately rather than waiting for it. The ID of the current process is written to
+// the file so that Owner can report who holds the lock.
+func TryLock(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, os.FileMode(0644))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lock(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	return &Lock{file: f}, nil
+}
+
+// Unlock releases the lock. The file is left in place, as removing it could allow two processes to hold locks on
+// different files at the same path.
+func (l *Lock) Unlock() error {
+	if err := unlock(l.file); err != nil {
+		_ = l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// Owner returns the ID of the process that last took the lock at the given path, or 0 if it isn't known.
+func Owner(path string) int {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid
+}
@@ -0,0 +1,47 @@
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "wadman.lock")
+
+	first, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() failed: %v", err)
+	}
+
+	if owner := Owner(path); owner != os.Getpid() {
+		t.Errorf("Owner() = %d, want %d", owner, os.Getpid())
+	}
+
+	if second, err := TryLock(path); err != ErrLocked {
+		t.Fatalf("second TryLock() = %v, %v, want ErrLocked", second, err)
+	}
+
+	if err := first.Unlock(); err != nil {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("lock file was removed by Unlock: %v", err)
+	}
+
+	second, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() after Unlock() failed: %v", err)
+	}
+
+	if err := second.Unlock(); err != nil {
+		t.Errorf("Unlock() failed: %v", err)
+	}
+}
+
+func TestOwner_Missing(t *testing.T) {
+	if owner := Owner(filepath.Join(t.TempDir(), "missing.lock")); owner != 0 {
+		t.Errorf("Owner() = %d, want 0", owner)
+	}
+}
@@ -0,0 +1,22 @@
+package filelock
+
+import (
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// lockOffset is the position of the byte that is locked. Windows locks prevent other processes reading the locked
+// region, so a byte well past the end of the file is used to leave the process ID readable.
+const lockOffset = 0x7fffffff
+
+func lock(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{Offset: lockOffset})
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return ErrLocked
+	}
+	return err
+}
+
+func unlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{Offset: lockOffset})
+}
@@ -0,0 +1,310 @@
+package wadman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/csmith/wadman/git"
+	"github.com/csmith/wadman/pkgmeta"
+	"github.com/csmith/wadman/wow"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	RegisterProvider(&gitProvider{})
+}
+
+// GitAddon is an addon built from the source in a git repository, optionally at a specific branch or tag. The
+// repository is mirrored into wadman's cache and packaged according to its .pkgmeta file, if it has one.
+type GitAddon struct {
+	BaseAddon
+	Url    string `json:"url"`
+	Ref    string `json:"ref,omitempty"`
+	Commit string `json:"commit"`
+}
+
+func NewGitAddon(url string, ref string) Addon {
+	return &GitAddon{BaseAddon: BaseAddon{AddonType: TypeGit}, Url: url, Ref: ref}
+}
+
+// DisplayName returns the name of the repository, without any ".git" suffix.
+func (g *GitAddon) DisplayName() string {
+	name := path.Base(strings.TrimSuffix(filepath.ToSlash(g.Url), "/"))
+	if i := strings.LastIndex(name, ":"); i > -1 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+func (g *GitAddon) ShortName() string {
+	if g.Ref != "" {
+		return fmt.Sprintf("git:%s#%s", g.Url, g.Ref)
+	}
+	return fmt.Sprintf("git:%s", g.Url)
+}
+
+// Locked returns a lockfile entry for the installed commit. The commit hash is used as both the file ID and
+// checksum, as git verifies the contents of commits itself.
+func (g *GitAddon) Locked() LockEntry {
+	return g.LockEntry(g.ShortName(), g.DisplayName(), g.Commit)
+}
+
+func (g *GitAddon) Install(ctx context.Context, w *wow.Install, release *Release, _ wow.ProgressFunc) error {
+	mirror, err := mirrorPath(g.Url)
+	if err != nil {
+		return err
+	}
+
+	if !git.HasCommit(ctx, mirror, release.FileId) {
+		if err := git.Mirror(ctx, g.Url, mirror); err != nil {
+			return err
+		}
+	}
+
+	work, err := ioutil.TempDir(filepath.Dir(mirror), "build-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	source := filepath.Join(work, "source")
+	if err := git.Export(ctx, mirror, release.FileId, source); err != nil {
+		return err
+	}
+
+	sources, err := g.build(ctx, source, filepath.Join(work, "package"), release.Version)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := w.ReplaceAddonsFromDirs(ctx, sources, false, g.Directories)
+	if err != nil {
+		return err
+	}
+
+	g.deployed(release, dirs, release.FileId)
+	g.Commit = release.FileId
+	return nil
+}
+
+// build packages the source exported from the repository, returning the addon directories that should be installed.
+// If the repository has a .pkgmeta file it is used to build the package, otherwise the source is used as-is.
+func (g *GitAddon) build(ctx context.Context, source string, target string, version string) (map[string]string, error) {
+	meta, err := pkgmeta.Load(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta == nil {
+		if err := pkgmeta.SubstituteKeywords(source, version); err != nil {
+			return nil, err
+		}
+		return wow.FindAddonDirs(source)
+	}
+
+	// If the root of the repository is an addon, its TOC file gives a better name than the repository
+	name := g.DisplayName()
+	if dirs, err := wow.FindAddonDirs(source); err == nil && len(dirs) == 1 {
+		for n, p := range dirs {
+			if p == source {
+				name = n
+			}
+		}
+	}
+
+	names, err := meta.Package(ctx, source, target, pkgmeta.Options{
+		Name:    name,
+		Version: version,
+		Fetch:   fetchExternal,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]string)
+	for _, n := range names {
+		sources[n] = filepath.Join(target, n)
+	}
+	return sources, nil
+}
+
+// fetchExternal exports an external repository referenced by a .pkgmeta file into the target directory.
+func fetchExternal(ctx context.Context, external pkgmeta.External, target string) error {
+	if !external.IsGit() {
+		return fmt.Errorf("only git externals are supported: %s", external.Url)
+	}
+
+	mirror, err := mirrorPath(external.Url)
+	if err != nil {
+		return err
+	}
+
+	if err := git.Mirror(ctx, external.Url, mirror); err != nil {
+		return err
+	}
+
+	ref := external.Ref()
+	if ref == "latest" {
+		if ref, err = git.LatestTag(ctx, mirror); err != nil {
+			return err
+		}
+	}
+
+	commit, err := git.ResolveRef(ctx, mirror, ref)
+	if err != nil {
+		return err
+	}
+
+	return git.Export(ctx, mirror, commit, target)
+}
+
+// mirrorPath returns the path in the cache that the repository at the given URL is mirrored to.
+func mirrorPath(url string) (string, error) {
+	cache, err := CachePath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cache, "git", wow.Checksum([]byte(url))[:16]), nil
+}
+
+type gitProvider struct{}
+
+func (p *gitProvider) Type() AddonType {
+	return TypeGit
+}
+
+func (p *gitProvider) Name() string {
+	return "Git"
+}
+
+// NewAddon creates an addon from a repository URL, optionally followed by a "#" and the branch or tag to use. Local
+// repositories may be given as paths, which are made absolute.
+func (p *gitProvider) NewAddon(id string) (Addon, error) {
+	url, ref := id, ""
+	if i := strings.LastIndex(id, "#"); i > -1 {
+		url, ref = id[:i], id[i+1:]
+	}
+
+	// Neither part may start with a dash, as git would treat them as options
+	if url == "" || strings.HasPrefix(url, "-") || strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid git repository: %s", id)
+	}
+
+	if _, err := os.Stat(url); err == nil {
+		if abs, err := filepath.Abs(url); err == nil {
+			url = abs
+		}
+	}
+
+	return NewGitAddon(url, ref), nil
+}
+
+func (p *gitProvider) Marshal(addon Addon) ([]byte, error) {
+	return json.Marshal(addon)
+}
+
+func (p *gitProvider) Unmarshal(data []byte) (Addon, error) {
+	addon := NewGitAddon("", "")
+	if err := json.Unmarshal(data, addon); err != nil {
+		return nil, err
+	}
+	return addon, nil
+}
+
+func (p *gitProvider) Search(context.Context, string) ([]SearchResult, error) {
+	return nil, nil
+}
+
+// Resolve updates the mirror of the repository and returns the commit that the addon's branch or tag refers to.
+func (p *gitProvider) Resolve(ctx context.Context, addon Addon, _ *wow.Install, _ Observer) (*Release, error) {
+	g, ok := addon.(*GitAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a git addon", addon.ShortName())
+	}
+
+	mirror, err := mirrorPath(g.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := git.Mirror(ctx, g.Url, mirror); err != nil {
+		return nil, err
+	}
+
+	commit, err := git.ResolveRef(ctx, mirror, g.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := git.Describe(ctx, mirror, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := git.CommitDate(ctx, mirror, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Release{
+		FileId:   commit,
+		Name:     g.DisplayName(),
+		Version:  version,
+		Url:      g.Url,
+		Checksum: commit,
+		Date:     date,
+	}, nil
+}
+
+// Details returns the commit the addon's branch or tag refers to, along with every tag in the repository.
+func (p *gitProvider) Details(ctx context.Context, addon Addon) (*AddonDetails, error) {
+	release, err := p.Resolve(ctx, addon, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	mirror, err := mirrorPath(addon.(*GitAddon).Url)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := git.Tags(ctx, mirror)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &AddonDetails{
+		Name:  addon.DisplayName(),
+		Files: []File{{Release: *release, Channel: ChannelRelease}},
+	}
+	for _, t := range tags {
+		if t.Commit == release.FileId {
+			continue
+		}
+
+		details.Files = append(details.Files, File{
+			Release: Release{
+				FileId:   t.Commit,
+				Name:     release.Name,
+				Version:  t.Name,
+				Url:      release.Url,
+				Checksum: t.Commit,
+				Date:     t.Date,
+			},
+			Channel: ChannelRelease,
+		})
+	}
+	if strings.HasPrefix(release.Url, "https://") || strings.HasPrefix(release.Url, "http://") {
+		details.Website = release.Url
+	}
+	return details, nil
+}
+
+func (p *gitProvider) Changelog(context.Context, Addon, *Release) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,237 @@
+package git
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mirror creates a bare mirror of the repository at the given URL in dir, or updates it if it already exists.
+func Mirror(ctx context.Context, url string, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err == nil {
+		_, err := run(ctx, dir, "remote", "update", "--prune")
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	// Clone into a temporary directory first, so an interrupted clone doesn't leave a broken mirror behind
+	tmp := fmt.Sprintf("%s.tmp", dir)
+	_ = os.RemoveAll(tmp)
+	if _, err := run(ctx, "", "clone", "--quiet", "--mirror", "--", url, tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+// HasCommit determines whether the repository in dir contains the given commit.
+func HasCommit(ctx context.Context, dir string, commit string) bool {
+	if strings.HasPrefix(commit, "-") {
+		return false
+	}
+
+	_, err := run(ctx, dir, "cat-file", "-e", fmt.Sprintf("%s^{commit}", commit))
+	return err == nil
+}
+
+// ResolveRef returns the full hash of the commit that the given branch, tag or commit refers to. If ref is empty, the
+// repository's default branch is used.
+func ResolveRef(ctx context.Context, dir string, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	} else if strings.HasPrefix(ref, "-") {
+		// Don't let refs be interpreted as options
+		return "", fmt.Errorf("unknown branch, tag or commit: %s", ref)
+	}
+
+	commit, err := run(ctx, dir, "rev-parse", "--verify", "--quiet", fmt.Sprintf("%s^{commit}", ref))
+	if err != nil {
+		return "", fmt.Errorf("unknown branch, tag or commit: %s", ref)
+	}
+	return commit, nil
+}
+
+// LatestTag returns the most recent tag reachable from the repository's default branch.
+func LatestTag(ctx context.Context, dir string) (string, error) {
+	tag, err := run(ctx, dir, "describe", "--tags", "--abbrev=0", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("no tags found")
+	}
+	return tag, nil
+}
+
+// Tag is a tag within a repository.
+type Tag struct {
+	Name   string
+	Commit string
+	Date   time.Time
+}
+
+// Tags returns all the tags in the repository, along with the commits they point to.
+func Tags(ctx context.Context, dir string) ([]Tag, error) {
+	out, err := run(ctx, dir, "for-each-ref", "--format=%(refname:short)%09%(objectname)%09%(*objectname)%09%(creatordate:iso-strict)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+
+		tag := Tag{Name: fields[0], Commit: fields[1]}
+		if fields[2] != "" {
+			// Annotated tags point to a tag object, which in turn points to the commit
+			tag.Commit = fields[2]
+		}
+		tag.Date, _ = time.Parse(time.RFC3339, fields[3])
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// Describe returns a human-readable version for the given commit, based on the most recent tag. If there are no
+// tags, the abbreviated commit hash is returned.
+func Describe(ctx context.Context, dir string, commit string) (string, error) {
+	if err := checkHash(commit); err != nil {
+		return "", err
+	}
+	return run(ctx, dir, "describe", "--tags", "--always", commit)
+}
+
+// CommitDate returns the time the given commit was made.
+func CommitDate(ctx context.Context, dir string, commit string) (time.Time, error) {
+	if err := checkHash(commit); err != nil {
+		return time.Time{}, err
+	}
+
+	date, err := run(ctx, dir, "show", "--no-patch", "--format=%cI", commit)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, date)
+}
+
+// Export writes the files in the given commit to the target directory, which will be created if necessary. The commit
+// must be given as a full hash, such as one returned by ResolveRef.
+func Export(ctx context.Context, dir string, commit string, target string) error {
+	if err := checkHash(commit); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "archive", "--format=tar", commit)
+	cmd.Dir = dir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if err := untar(out, target); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// checkHash ensures the commit is a full hex hash, so that values read from the lockfile can't be interpreted as
+// options or other revision expressions.
+func checkHash(commit string) error {
+	if len(commit) != 40 && len(commit) != 64 {
+		return fmt.Errorf("invalid commit hash: %s", commit)
+	}
+
+	for _, c := range commit {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return fmt.Errorf("invalid commit hash: %s", commit)
+		}
+	}
+	return nil
+}
+
+// untar extracts the regular files and directories from a tar stream into the target directory.
+func untar(r io.Reader, target string) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(header.Name)
+		if strings.HasPrefix(filepath.Clean(name), "..") || filepath.IsAbs(name) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		path := filepath.Join(target, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.FileMode(0755)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+				return err
+			}
+
+			out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(out, reader)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// run executes git with the given arguments in dir, returning its trimmed output.
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s failed: %v", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,310 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/csmith/wadman/pkgmeta"
+)
+
+// gitCmd runs git in the given directory, failing the test if it doesn't succeed.
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), os.FileMode(0644)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// bareRepo creates a repository containing the given commits, each of which is a set of files to write, and returns
+// the path of a bare clone of it along with the hash of each commit.
+func bareRepo(t *testing.T, commits ...map[string]string) (string, []string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	base := t.TempDir()
+	work := filepath.Join(base, "work")
+	if err := os.Mkdir(work, os.FileMode(0755)); err != nil {
+		t.Fatal(err)
+	}
+
+	gitCmd(t, work, "init", "--quiet")
+
+	var hashes []string
+	for i, files := range commits {
+		writeFiles(t, work, files)
+		gitCmd(t, work, "add", "--all")
+		gitCmd(t, work, "commit", "--quiet", "-m", "commit "+string(rune('a'+i)))
+		hashes = append(hashes, gitCmd(t, work, "rev-parse", "HEAD"))
+	}
+
+	bare := filepath.Join(base, "repo.git")
+	gitCmd(t, base, "clone", "--quiet", "--bare", work, bare)
+	return bare, hashes
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestMirrorAndResolveRef(t *testing.T) {
+	ctx := context.Background()
+	repo, hashes := bareRepo(t,
+		map[string]string{"Addon.toc": "## Title: Addon\n"},
+		map[string]string{"Addon.lua": "print('hi')\n"},
+	)
+	gitCmd(t, repo, "tag", "v1.0", hashes[0])
+
+	mirror := filepath.Join(t.TempDir(), "cache", "mirror")
+	if err := Mirror(ctx, repo, mirror); err != nil {
+		t.Fatalf("Mirror() failed: %v", err)
+	}
+
+	if _, err := os.Stat(mirror + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary clone was left behind: %v", err)
+	}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "", want: hashes[1]},
+		{ref: "main", want: hashes[1]},
+		{ref: "v1.0", want: hashes[0]},
+		{ref: hashes[0][:10], want: hashes[0]},
+		{ref: "missing", wantErr: true},
+		{ref: "--all", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveRef(ctx, mirror, tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ResolveRef(%q) = %s, want error", tt.ref, got)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveRef(%q) = %s, %v, want %s", tt.ref, got, err, tt.want)
+		}
+	}
+
+	if tag, err := LatestTag(ctx, mirror); err != nil || tag != "v1.0" {
+		t.Errorf("LatestTag() = %s, %v, want v1.0", tag, err)
+	}
+
+	// Updating an existing mirror picks up new commits
+	work := filepath.Join(filepath.Dir(repo), "work")
+	writeFiles(t, work, map[string]string{"Addon.lua": "print('updated')\n"})
+	gitCmd(t, work, "commit", "--quiet", "--all", "-m", "update")
+	gitCmd(t, work, "push", "--quiet", repo, "HEAD:main")
+	latest := gitCmd(t, work, "rev-parse", "HEAD")
+
+	if HasCommit(ctx, mirror, latest) {
+		t.Fatalf("HasCommit() found %s before the mirror was updated", latest)
+	}
+
+	if err := Mirror(ctx, repo, mirror); err != nil {
+		t.Fatalf("Mirror() failed to update: %v", err)
+	}
+
+	if !HasCommit(ctx, mirror, latest) {
+		t.Errorf("HasCommit() didn't find %s after the mirror was updated", latest)
+	}
+
+	if HasCommit(ctx, mirror, "--all") {
+		t.Errorf("HasCommit() accepted an option as a commit")
+	}
+}
+
+func TestMirrorDoesNotTreatUrlAsOption(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	err := Mirror(context.Background(), "--upload-pack=touch "+marker, filepath.Join(dir, "mirror"))
+	if err == nil {
+		t.Errorf("Mirror() succeeded for an invalid URL")
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("Mirror() ran the upload pack given in the URL")
+	}
+}
+
+func TestExportRejectsInvalidCommits(t *testing.T) {
+	ctx := context.Background()
+	repo, hashes := bareRepo(t, map[string]string{"Addon.toc": "## Title: Addon\n"})
+
+	mirror := filepath.Join(t.TempDir(), "mirror")
+	if err := Mirror(ctx, repo, mirror); err != nil {
+		t.Fatalf("Mirror() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.tar")
+	tests := []string{
+		"--all",
+		"--output=" + output,
+		"--remote=" + repo,
+		hashes[0][:10],
+		"HEAD",
+		strings.ToUpper(hashes[0]),
+	}
+	for _, commit := range tests {
+		if err := Export(ctx, mirror, commit, filepath.Join(dir, "target")); err == nil {
+			t.Errorf("Export(%q) succeeded, want error", commit)
+		}
+
+		if date, err := CommitDate(ctx, mirror, commit); err == nil {
+			t.Errorf("CommitDate(%q) = %v, want error", commit, date)
+		}
+
+		if version, err := Describe(ctx, mirror, commit); err == nil {
+			t.Errorf("Describe(%q) = %s, want error", commit, version)
+		}
+	}
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("Export() wrote an archive to the path given in an option")
+	}
+
+	if _, err := CommitDate(ctx, mirror, hashes[0]); err != nil {
+		t.Errorf("CommitDate() of a full hash failed: %v", err)
+	}
+}
+
+func TestExportAndPackage(t *testing.T) {
+	ctx := context.Background()
+	lib, libHashes := bareRepo(t, map[string]string{
+		"LibThing.lua": "-- LibThing @project-version@\n",
+	})
+
+	repo, hashes := bareRepo(t, map[string]string{
+		".pkgmeta": strings.Join([]string{
+			"package-as: MyAddon",
+			"ignore:",
+			"  - Tests",
+			"  - \"*.md\"",
+			"externals:",
+			"  Libs/LibThing:",
+			"    url: " + lib,
+			"    type: git",
+			"move-folders:",
+			"  MyAddon/Modules/Extra: MyAddon_Extra",
+			"",
+		}, "\n"),
+		"MyAddon.toc":                     "## Version: @project-version@\nCore.lua\n",
+		"Core.lua":                        "print('core')\n",
+		"README.md":                       "Not packaged\n",
+		"Tests/Test.lua":                  "Not packaged\n",
+		"Modules/Extra/MyAddon_Extra.toc": "## Title: Extra\n",
+	})
+
+	mirror := filepath.Join(t.TempDir(), "mirror")
+	if err := Mirror(ctx, repo, mirror); err != nil {
+		t.Fatalf("Mirror() failed: %v", err)
+	}
+
+	source := filepath.Join(t.TempDir(), "source")
+	if err := Export(ctx, mirror, hashes[0], source); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	meta, err := pkgmeta.Load(source)
+	if err != nil || meta == nil {
+		t.Fatalf("pkgmeta.Load() = %v, %v", meta, err)
+	}
+
+	fetched := 0
+	target := filepath.Join(t.TempDir(), "package")
+	names, err := meta.Package(ctx, source, target, pkgmeta.Options{
+		Name:    "fallback",
+		Version: "v1.2.3",
+		Fetch: func(ctx context.Context, external pkgmeta.External, dir string) error {
+			fetched++
+			libMirror := filepath.Join(filepath.Dir(mirror), "lib")
+			if err := Mirror(ctx, external.Url, libMirror); err != nil {
+				return err
+			}
+
+			commit, err := ResolveRef(ctx, libMirror, external.Ref())
+			if err != nil {
+				return err
+			}
+
+			if commit != libHashes[0] {
+				t.Errorf("external resolved to %s, want %s", commit, libHashes[0])
+			}
+			return Export(ctx, libMirror, commit, dir)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Package() failed: %v", err)
+	}
+
+	sort.Strings(names)
+	if strings.Join(names, ",") != "MyAddon,MyAddon_Extra" {
+		t.Errorf("Package() = %v, want [MyAddon MyAddon_Extra]", names)
+	}
+
+	if fetched != 1 {
+		t.Errorf("fetched %d externals, want 1", fetched)
+	}
+
+	if got := readFile(t, filepath.Join(target, "MyAddon", "MyAddon.toc")); got != "## Version: v1.2.3\nCore.lua\n" {
+		t.Errorf("keywords weren't substituted in the TOC file: %q", got)
+	}
+
+	if got := readFile(t, filepath.Join(target, "MyAddon", "Libs", "LibThing", "LibThing.lua")); got != "-- LibThing @project-version@\n" {
+		// Keywords belong to the external's own project, so are left alone
+		t.Errorf("external wasn't packaged correctly: %q", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(target, "MyAddon_Extra", "MyAddon_Extra.toc")); err != nil {
+		t.Errorf("folder wasn't moved: %v", err)
+	}
+
+	for _, path := range []string{"README.md", "Tests", ".pkgmeta", filepath.Join("Modules", "Extra")} {
+		if _, err := os.Stat(filepath.Join(target, "MyAddon", path)); !os.IsNotExist(err) {
+			t.Errorf("%s shouldn't be in the package", path)
+		}
+	}
+}
@@ -3,6 +3,10 @@ module github.com/csmith/wadman
 go 1.14
 
 require (
+	github.com/mattn/go-runewidth v0.0.7
 	github.com/olekukonko/tablewriter v0.0.4
 	github.com/spf13/cobra v1.0.0
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68
+	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,273 @@
+package wadman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/csmith/wadman/wow"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	RegisterProvider(&fileProvider{})
+	RegisterProvider(&directoryProvider{})
+}
+
+// localVersion is the format used to describe the version of local addons, which is the time they were last
+// modified. Updates are detected using checksums rather than versions, as described on each provider's Resolve.
+const localVersion = "2006-01-02 15:04:05"
+
+// FileAddon is an addon installed from a ZIP file on the local file system. It is reinstalled whenever the contents
+// of the file change.
+type FileAddon struct {
+	BaseAddon
+	Path string `json:"path"`
+}
+
+func NewFileAddon(path string) Addon {
+	return &FileAddon{BaseAddon: BaseAddon{AddonType: TypeFile}, Path: path}
+}
+
+func (f *FileAddon) DisplayName() string {
+	return strings.TrimSuffix(filepath.Base(f.Path), filepath.Ext(f.Path))
+}
+
+func (f *FileAddon) ShortName() string {
+	return fmt.Sprintf("file:%s", f.Path)
+}
+
+// Locked returns a lockfile entry for the installed file. Local files have no IDs, so the checksum of the file is
+// used to identify it instead.
+func (f *FileAddon) Locked() LockEntry {
+	return f.LockEntry(f.ShortName(), f.DisplayName(), f.Checksum)
+}
+
+func (f *FileAddon) Install(ctx context.Context, w *wow.Install, release *Release, _ wow.ProgressFunc) error {
+	b, err := ioutil.ReadFile(release.Url)
+	if err != nil {
+		return err
+	}
+
+	checksum := wow.Checksum(b)
+	if release.Checksum != "" && !strings.EqualFold(checksum, release.Checksum) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", release.Url, release.Checksum, checksum)
+	}
+
+	dirs, err := w.ReplaceAddons(ctx, bytes.NewReader(b), f.Directories)
+	if err != nil {
+		return err
+	}
+
+	f.deployed(release, dirs, checksum)
+	return nil
+}
+
+// DirectoryAddon is an addon installed from a directory on the local file system, such as a source checkout. The
+// directory may either be an addon itself, or contain several addons. It is reinstalled whenever any file within
+// it is added, removed or modified.
+type DirectoryAddon struct {
+	BaseAddon
+	Path string `json:"path"`
+	// Link indicates that the addon should be symlinked into the addons directory rather than copied.
+	Link bool `json:"link,omitempty"`
+}
+
+func NewDirectoryAddon(path string) Addon {
+	return &DirectoryAddon{BaseAddon: BaseAddon{AddonType: TypeDirectory}, Path: path}
+}
+
+func (d *DirectoryAddon) DisplayName() string {
+	return filepath.Base(d.Path)
+}
+
+func (d *DirectoryAddon) ShortName() string {
+	return fmt.Sprintf("dir:%s", d.Path)
+}
+
+// Locked returns a lockfile entry for the installed files. Directories have no IDs, so a fingerprint of the files
+// they contain is used to identify them instead.
+func (d *DirectoryAddon) Locked() LockEntry {
+	return d.LockEntry(d.ShortName(), d.DisplayName(), d.Checksum)
+}
+
+func (d *DirectoryAddon) Install(ctx context.Context, w *wow.Install, release *Release, _ wow.ProgressFunc) error {
+	sources, err := wow.FindAddonDirs(release.Url)
+	if err != nil {
+		return err
+	}
+
+	fingerprint, _, err := wow.Fingerprint(sources)
+	if err != nil {
+		return err
+	}
+
+	if release.Checksum != "" && fingerprint != release.Checksum {
+		return fmt.Errorf("the contents of %s changed while it was being installed", release.Url)
+	}
+
+	dirs, err := w.ReplaceAddonsFromDirs(ctx, sources, d.Link, d.Directories)
+	if err != nil {
+		return err
+	}
+
+	d.deployed(release, dirs, fingerprint)
+	return nil
+}
+
+type fileProvider struct{}
+
+func (p *fileProvider) Type() AddonType {
+	return TypeFile
+}
+
+func (p *fileProvider) Name() string {
+	return "Local file"
+}
+
+func (p *fileProvider) NewAddon(id string) (Addon, error) {
+	path, err := filepath.Abs(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %s", id)
+	}
+	return NewFileAddon(path), nil
+}
+
+func (p *fileProvider) Marshal(addon Addon) ([]byte, error) {
+	return json.Marshal(addon)
+}
+
+func (p *fileProvider) Unmarshal(data []byte) (Addon, error) {
+	addon := NewFileAddon("")
+	if err := json.Unmarshal(data, addon); err != nil {
+		return nil, err
+	}
+	return addon, nil
+}
+
+func (p *fileProvider) Search(context.Context, string) ([]SearchResult, error) {
+	return nil, nil
+}
+
+// Resolve describes the current contents of the file. The checksum of the file is used as its file ID, so it is only
+// reinstalled if the contents change; the modification time is only used as the version shown to users.
+func (p *fileProvider) Resolve(_ context.Context, addon Addon, _ *wow.Install, _ Observer) (*Release, error) {
+	f, ok := addon.(*FileAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a local file addon", addon.ShortName())
+	}
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := wow.Checksum(b)
+	return &Release{
+		FileId:   checksum,
+		Name:     f.DisplayName(),
+		Version:  info.ModTime().Format(localVersion),
+		Url:      f.Path,
+		Checksum: checksum,
+		Date:     info.ModTime(),
+	}, nil
+}
+
+func (p *fileProvider) Details(ctx context.Context, addon Addon) (*AddonDetails, error) {
+	return localDetails(ctx, p, addon)
+}
+
+func (p *fileProvider) Changelog(context.Context, Addon, *Release) (string, error) {
+	return "", nil
+}
+
+type directoryProvider struct{}
+
+func (p *directoryProvider) Type() AddonType {
+	return TypeDirectory
+}
+
+func (p *directoryProvider) Name() string {
+	return "Local directory"
+}
+
+func (p *directoryProvider) NewAddon(id string) (Addon, error) {
+	path, err := filepath.Abs(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %s", id)
+	}
+	return NewDirectoryAddon(path), nil
+}
+
+func (p *directoryProvider) Marshal(addon Addon) ([]byte, error) {
+	return json.Marshal(addon)
+}
+
+func (p *directoryProvider) Unmarshal(data []byte) (Addon, error) {
+	addon := NewDirectoryAddon("")
+	if err := json.Unmarshal(data, addon); err != nil {
+		return nil, err
+	}
+	return addon, nil
+}
+
+func (p *directoryProvider) Search(context.Context, string) ([]SearchResult, error) {
+	return nil, nil
+}
+
+// Resolve describes the current contents of the directory. A fingerprint of the files is used as the file ID, which
+// changes if any file is added, removed, resized or touched; the latest modification time is used as the version.
+func (p *directoryProvider) Resolve(_ context.Context, addon Addon, _ *wow.Install, _ Observer) (*Release, error) {
+	d, ok := addon.(*DirectoryAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a local directory addon", addon.ShortName())
+	}
+
+	sources, err := wow.FindAddonDirs(d.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, modified, err := wow.Fingerprint(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Release{
+		FileId:   fingerprint,
+		Name:     d.DisplayName(),
+		Version:  modified.Format(localVersion),
+		Url:      d.Path,
+		Checksum: fingerprint,
+		Date:     modified,
+	}, nil
+}
+
+func (p *directoryProvider) Details(ctx context.Context, addon Addon) (*AddonDetails, error) {
+	return localDetails(ctx, p, addon)
+}
+
+func (p *directoryProvider) Changelog(context.Context, Addon, *Release) (string, error) {
+	return "", nil
+}
+
+// localDetails describes a local addon, which only ever has a single file available: its current contents.
+func localDetails(ctx context.Context, provider Provider, addon Addon) (*AddonDetails, error) {
+	release, err := provider.Resolve(ctx, addon, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddonDetails{
+		Name:  addon.DisplayName(),
+		Files: []File{{Release: *release, Channel: ChannelRelease}},
+	}, nil
+}
@@ -0,0 +1,95 @@
+package wadman
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileProvider_Resolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "MyAddon.zip")
+	if err := ioutil.WriteFile(path, []byte("first"), os.FileMode(0644)); err != nil {
+		t.Fatal(err)
+	}
+
+	addon := NewFileAddon(path).(*FileAddon)
+	first, newer, err := CheckForUpdate(context.Background(), addon, nil, nil)
+	if err != nil || !newer {
+		t.Fatalf("CheckForUpdate() = %v, %t, %v, want an update for a new addon", first, newer, err)
+	}
+	addon.deployed(first, []string{"MyAddon"}, first.Checksum)
+
+	// Touching the file changes the version shown, but not the file ID
+	later := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	touched, newer, err := CheckForUpdate(context.Background(), addon, nil, nil)
+	if err != nil || newer {
+		t.Errorf("CheckForUpdate() after touching = %v, %t, %v, want no update", touched, newer, err)
+	} else if touched.Version != later.Format(localVersion) {
+		t.Errorf("CheckForUpdate() version = %s, want %s", touched.Version, later.Format(localVersion))
+	}
+
+	// Changing the contents is an update, even if the modification time is the same
+	if err := ioutil.WriteFile(path, []byte("second"), os.FileMode(0644)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, newer, err := CheckForUpdate(context.Background(), addon, nil, nil); err != nil || !newer {
+		t.Errorf("CheckForUpdate() after changing = %v, %t, %v, want an update", changed, newer, err)
+	}
+}
+
+func TestDirectoryProvider_Resolve(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "MyAddon")
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		t.Fatal(err)
+	}
+
+	toc := filepath.Join(dir, "MyAddon.toc")
+	if err := ioutil.WriteFile(toc, []byte("## Title: MyAddon\n"), os.FileMode(0644)); err != nil {
+		t.Fatal(err)
+	}
+
+	addon := NewDirectoryAddon(dir).(*DirectoryAddon)
+	first, _, err := CheckForUpdate(context.Background(), addon, nil, nil)
+	if err != nil {
+		t.Fatalf("CheckForUpdate() failed: %v", err)
+	}
+	addon.deployed(first, []string{"MyAddon"}, first.Checksum)
+
+	if release, newer, err := CheckForUpdate(context.Background(), addon, nil, nil); err != nil || newer {
+		t.Errorf("CheckForUpdate() of an unchanged directory = %v, %t, %v, want no update", release, newer, err)
+	}
+
+	// Directory contents aren't read, so touching a file is treated as an update
+	later := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(toc, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	touched, newer, err := CheckForUpdate(context.Background(), addon, nil, nil)
+	if err != nil || !newer {
+		t.Errorf("CheckForUpdate() after touching = %v, %t, %v, want an update", touched, newer, err)
+	} else if touched.Version != later.Format(localVersion) {
+		t.Errorf("CheckForUpdate() version = %s, want %s", touched.Version, later.Format(localVersion))
+	}
+	addon.deployed(touched, []string{"MyAddon"}, touched.Checksum)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "Core.lua"), []byte("print('hi')\n"), os.FileMode(0644)); err != nil {
+		t.Fatal(err)
+	}
+
+	if added, newer, err := CheckForUpdate(context.Background(), addon, nil, nil); err != nil || !newer {
+		t.Errorf("CheckForUpdate() after adding a file = %v, %t, %v, want an update", added, newer, err)
+	}
+}
@@ -0,0 +1,109 @@
+package wadman
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockfileVersion specifies the maximum version of the lockfile this build of wadman supports
+const lockfileVersion = 1
+
+// LockEntry records the exact file that was installed for an addon, so that it can be reproduced elsewhere.
+type LockEntry struct {
+	// Id is the short name of the addon, e.g. "curse:3358".
+	Id string `json:"id"`
+	// Name is the display name of the addon.
+	Name string `json:"name"`
+	// FileId identifies the installed file within the addon's source.
+	FileId string `json:"file_id"`
+	// Version is the human-readable version of the installed file.
+	Version string `json:"version"`
+	// Url is the address the file was downloaded from.
+	Url string `json:"url"`
+	// Checksum is the hex-encoded SHA-256 checksum of the downloaded file.
+	Checksum string `json:"checksum"`
+}
+
+// Release converts the lock entry into a release that can be installed.
+func (e LockEntry) Release() *Release {
+	return &Release{
+		FileId:   e.FileId,
+		Name:     e.Name,
+		Version:  e.Version,
+		Url:      e.Url,
+		Checksum: e.Checksum,
+	}
+}
+
+type Lockfile struct {
+	Addons []LockEntry
+}
+
+// NewLockfile creates a lockfile describing the currently installed versions of the given addons.
+func NewLockfile(addons []Addon) *Lockfile {
+	lock := &Lockfile{}
+	for i := range addons {
+		lock.Addons = append(lock.Addons, addons[i].Locked())
+	}
+	return lock
+}
+
+// Entry returns the lockfile entry for the addon with the given short name, or nil if it is not present.
+func (l *Lockfile) Entry(id string) *LockEntry {
+	for i := range l.Addons {
+		if l.Addons[i].Id == id {
+			return &l.Addons[i]
+		}
+	}
+	return nil
+}
+
+func LockfilePath() (string, error) {
+	basePath, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(basePath, "wadman", "wadman.lock"), nil
+}
+
+func LoadLockfile(path string) (*Lockfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	data := &struct {
+		Version int         `json:"version"`
+		Addons  []LockEntry `json:"addons"`
+	}{}
+	if err := json.NewDecoder(f).Decode(data); err != nil {
+		return nil, err
+	}
+
+	if data.Version > lockfileVersion {
+		return nil, fmt.Errorf("lockfile version %d requires a new version of wadman", data.Version)
+	}
+
+	return &Lockfile{Addons: data.Addons}, nil
+}
+
+func SaveLockfile(path string, lock *Lockfile) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	data := &struct {
+		Version int         `json:"version"`
+		Addons  []LockEntry `json:"addons"`
+	}{
+		lockfileVersion,
+		lock.Addons,
+	}
+
+	return writeJson(path, data)
+}
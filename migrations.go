@@ -0,0 +1,216 @@
+package wadman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// configMigration upgrades the raw contents of a config file from the previous version to the given version.
+type configMigration struct {
+	version     int
+	description string
+	// apply modifies the config in place, returning a human-readable description of each change it made.
+	apply func(data map[string]interface{}) ([]string, error)
+}
+
+// configMigrations are applied in order to bring old config files up to configVersion.
+var configMigrations []configMigration
+
+func init() {
+	registerConfigMigration(2, "Use the base game directory as the install path", migrateInstallPath)
+	registerConfigMigration(3, "Record the type of each addon", migrateAddonTypes)
+}
+
+// registerConfigMigration adds a migration to the pipeline. Migrations must be registered in order, with each one
+// upgrading the config by exactly one version.
+func registerConfigMigration(version int, description string, apply func(data map[string]interface{}) ([]string, error)) {
+	expected := 2
+	if len(configMigrations) > 0 {
+		expected = configMigrations[len(configMigrations)-1].version + 1
+	}
+
+	if version != expected || version > configVersion {
+		panic(fmt.Sprintf("config migration to version %d registered out of order", version))
+	}
+
+	configMigrations = append(configMigrations, configMigration{version: version, description: description, apply: apply})
+}
+
+// ConfigMigration describes the changes made by a migration when upgrading a config file.
+type ConfigMigration struct {
+	Version     int      `json:"version"`
+	Description string   `json:"description"`
+	Changes     []string `json:"changes"`
+}
+
+// MigrateConfigData upgrades the raw contents of a config file to the current version, returning the version the
+// data started at, the upgraded JSON, and the migrations that were applied. Data that is already at the current
+// version is returned unchanged.
+func MigrateConfigData(b []byte) (int, []byte, []ConfigMigration, error) {
+	data := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return 0, nil, nil, err
+	}
+
+	version, err := configDataVersion(data)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	if version > configVersion {
+		return version, nil, nil, fmt.Errorf("config file version %d requires a new version of wadman", version)
+	}
+
+	if version == configVersion {
+		return version, b, nil, nil
+	}
+
+	var applied []ConfigMigration
+	current := version
+	for _, m := range configMigrations {
+		if m.version <= current {
+			continue
+		}
+
+		changes, err := m.apply(data)
+		if err != nil {
+			return version, nil, nil, fmt.Errorf("unable to migrate config to version %d: %v", m.version, err)
+		}
+
+		current = m.version
+		data["version"] = current
+		applied = append(applied, ConfigMigration{Version: m.version, Description: m.description, Changes: changes})
+	}
+
+	if current != configVersion {
+		return version, nil, nil, fmt.Errorf("no migration from config version %d to %d", current, configVersion)
+	}
+
+	res, err := json.Marshal(data)
+	if err != nil {
+		return version, nil, nil, err
+	}
+	return version, res, applied, nil
+}
+
+// PendingConfigMigrations reads the config file at the given path and reports its version, along with the
+// migrations that would be applied the next time it is saved. The file is not modified.
+func PendingConfigMigrations(path string) (int, []ConfigMigration, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	version, _, migrations, err := MigrateConfigData(b)
+	return version, migrations, err
+}
+
+// BackupConfig copies the config file at the given path if it was written by an older version of wadman, so that
+// it can be restored if the upgraded file doesn't work as expected. The path of the backup is returned, or an empty
+// string if no backup was needed or one already exists.
+func BackupConfig(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	data := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		// There's no point trying to keep a file that can't be loaded
+		return "", nil
+	}
+
+	version, err := configDataVersion(data)
+	if err != nil || version >= configVersion {
+		return "", nil
+	}
+
+	if version < 1 {
+		version = 1
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if _, err := os.Stat(backup); err == nil {
+		return "", nil
+	}
+
+	if err := ioutil.WriteFile(backup, b, privateMode(path)); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
+// configDataVersion returns the version of a raw config file. Files from before the version field was introduced are
+// reported as version 1.
+func configDataVersion(data map[string]interface{}) (int, error) {
+	raw, ok := data["version"]
+	if !ok || raw == nil {
+		return 1, nil
+	}
+
+	n, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid config version: %v", raw)
+	}
+
+	version, err := n.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid config version: %v", raw)
+	}
+	return int(version), nil
+}
+
+// migrateInstallPath changes the install path from the addons directory to the base _retail_ directory.
+func migrateInstallPath(data map[string]interface{}) ([]string, error) {
+	path, _ := data["install_path"].(string)
+	if path == "" {
+		return nil, nil
+	}
+
+	base := filepath.Dir(filepath.Dir(path))
+	data["install_path"] = base
+	return []string{fmt.Sprintf("install_path changed from %s to %s", path, base)}, nil
+}
+
+// migrateAddonTypes sets the type of addons that don't have one to DefaultType, as all addons were from that source
+// before the type field was added.
+func migrateAddonTypes(data map[string]interface{}) ([]string, error) {
+	addons, _ := data["addons"].([]interface{})
+
+	var changes []string
+	for i := range addons {
+		addon, ok := addons[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("addon %d is not an object", i)
+		}
+
+		if t, _ := addon["type"].(string); t != "" {
+			continue
+		}
+
+		addon["type"] = string(DefaultType)
+		changes = append(changes, fmt.Sprintf("type of addon %s set to %s", migrationAddonName(i, addon), DefaultType))
+	}
+	return changes, nil
+}
+
+// migrationAddonName describes an addon in a raw config file for reporting migration changes.
+func migrationAddonName(index int, addon map[string]interface{}) string {
+	if name, _ := addon["name"].(string); name != "" {
+		return fmt.Sprintf("'%s'", name)
+	}
+	if id, ok := addon["id"]; ok {
+		return fmt.Sprintf("%v", id)
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
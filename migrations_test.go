@@ -0,0 +1,242 @@
+package wadman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func readConfigFixture(t *testing.T, version int) []byte {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "config", fmt.Sprintf("v%d.json", version)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// decodeConfigJson decodes raw config data so that files can be compared regardless of formatting.
+func decodeConfigJson(t *testing.T, b []byte) map[string]interface{} {
+	t.Helper()
+
+	data := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b)
+	}
+	return data
+}
+
+func TestMigrateConfigData(t *testing.T) {
+	installPath := ConfigMigration{
+		Version:     2,
+		Description: "Use the base game directory as the install path",
+		Changes: []string{fmt.Sprintf(
+			"install_path changed from /games/World of Warcraft/_retail_/Interface/AddOns to %s",
+			filepath.Dir(filepath.Dir("/games/World of Warcraft/_retail_/Interface/AddOns")),
+		)},
+	}
+	addonTypes := ConfigMigration{
+		Version:     3,
+		Description: "Record the type of each addon",
+		Changes:     []string{"type of addon 'Details! Damage Meter' set to curse"},
+	}
+
+	tests := []struct {
+		version    int
+		migrations []ConfigMigration
+	}{
+		{version: 1, migrations: []ConfigMigration{installPath, addonTypes}},
+		{version: 2, migrations: []ConfigMigration{addonTypes}},
+		{version: 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("v%d", tt.version), func(t *testing.T) {
+			if runtime.GOOS == "windows" && tt.version == 1 {
+				t.Skip("fixture install path uses Unix separators")
+			}
+
+			version, migrated, migrations, err := MigrateConfigData(readConfigFixture(t, tt.version))
+			if err != nil {
+				t.Fatalf("MigrateConfigData() failed: %v", err)
+			}
+
+			if version != tt.version {
+				t.Errorf("MigrateConfigData() version = %d, want %d", version, tt.version)
+			}
+
+			if !reflect.DeepEqual(migrations, tt.migrations) {
+				t.Errorf("MigrateConfigData() migrations = %+v, want %+v", migrations, tt.migrations)
+			}
+
+			if got, want := decodeConfigJson(t, migrated), decodeConfigJson(t, readConfigFixture(t, configVersion)); !reflect.DeepEqual(got, want) {
+				t.Errorf("MigrateConfigData() = %s, want %s", migrated, readConfigFixture(t, configVersion))
+			}
+		})
+	}
+}
+
+func TestMigrateConfigData_Errors(t *testing.T) {
+	tests := []string{
+		`not json`,
+		fmt.Sprintf(`{"version": %d}`, configVersion+1),
+		`{"version": "three"}`,
+		`{"version": 1.5}`,
+		`{"addons": ["not an object"]}`,
+	}
+	for _, input := range tests {
+		if _, _, _, err := MigrateConfigData([]byte(input)); err == nil {
+			t.Errorf("MigrateConfigData(%s) succeeded, want error", input)
+		}
+	}
+}
+
+func TestBackupConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	original := readConfigFixture(t, 1)
+	if err := ioutil.WriteFile(path, original, os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := BackupConfig(path)
+	if err != nil {
+		t.Fatalf("BackupConfig() failed: %v", err)
+	}
+
+	if backup != path+".v1.bak" {
+		t.Fatalf("BackupConfig() = %s, want %s.v1.bak", backup, path)
+	}
+
+	if b, err := ioutil.ReadFile(backup); err != nil || !bytes.Equal(b, original) {
+		t.Errorf("backup contents = %s, %v, want the original file", b, err)
+	}
+
+	if info, err := os.Stat(backup); runtime.GOOS != "windows" && (err != nil || info.Mode().Perm() != 0600) {
+		t.Errorf("backup mode = %v, %v, want the original file's mode", info.Mode().Perm(), err)
+	}
+
+	// A later save of a file at the same version must not replace the original backup
+	if err := ioutil.WriteFile(path, []byte(`{"install_path": "/elsewhere", "addons": []}`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	if backup, err := BackupConfig(path); err != nil || backup != "" {
+		t.Errorf("BackupConfig() = %s, %v, want no new backup", backup, err)
+	}
+
+	if b, err := ioutil.ReadFile(path + ".v1.bak"); err != nil || !bytes.Equal(b, original) {
+		t.Errorf("backup was overwritten: %s, %v", b, err)
+	}
+
+	// Older files get their own backup
+	if err := ioutil.WriteFile(path, readConfigFixture(t, 2), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	if backup, err := BackupConfig(path); err != nil || backup != path+".v2.bak" {
+		t.Errorf("BackupConfig() = %s, %v, want %s.v2.bak", backup, err, path)
+	}
+}
+
+func TestBackupConfig_NotNeeded(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := map[string][]byte{
+		"current.json": readConfigFixture(t, configVersion),
+		"invalid.json": []byte("not json"),
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, content, os.FileMode(0644)); err != nil {
+			t.Fatal(err)
+		}
+
+		if backup, err := BackupConfig(path); err != nil || backup != "" {
+			t.Errorf("BackupConfig(%s) = %s, %v, want no backup", name, backup, err)
+		}
+	}
+
+	if backup, err := BackupConfig(filepath.Join(dir, "missing.json")); err != nil || backup != "" {
+		t.Errorf("BackupConfig() of a missing file = %s, %v, want no backup", backup, err)
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != len(tests) {
+		t.Errorf("found %d files, want no backups to have been written", len(files))
+	}
+}
+
+func TestRegisterConfigMigration_OutOfOrder(t *testing.T) {
+	original := configMigrations
+	defer func() {
+		configMigrations = original
+	}()
+
+	noop := func(map[string]interface{}) ([]string, error) {
+		return nil, nil
+	}
+
+	expectPanic := func(existing []configMigration, version int) {
+		t.Helper()
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("registering version %d after %d migrations didn't panic", version, len(existing))
+			}
+		}()
+
+		// Copy the migrations so that a successful registration can't modify the real ones
+		configMigrations = append([]configMigration(nil), existing...)
+		registerConfigMigration(version, "test", noop)
+	}
+
+	expectPanic(nil, 1)
+	expectPanic(nil, 3)
+	expectPanic(original[:1], 2)
+	expectPanic(original[:1], 4)
+	expectPanic(original, configVersion+1)
+
+	configMigrations = nil
+	registerConfigMigration(2, "test", noop)
+	if len(configMigrations) != 1 || configMigrations[0].version != 2 {
+		t.Errorf("registering the first migration failed: %+v", configMigrations)
+	}
+}
+
+func TestUpgradeConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	original := readConfigFixture(t, 1)
+	if err := ioutil.WriteFile(path, original, os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+
+	if backup, err := UpgradeConfig(path, config); err != nil || backup != path+".v1.bak" {
+		t.Fatalf("UpgradeConfig() = %s, %v, want %s.v1.bak", backup, err, path)
+	}
+
+	if b, err := ioutil.ReadFile(path + ".v1.bak"); err != nil || !bytes.Equal(b, original) {
+		t.Errorf("backup contents = %s, %v, want the original file", b, err)
+	}
+
+	if version, migrations, err := PendingConfigMigrations(path); err != nil || version != configVersion || len(migrations) != 0 {
+		t.Errorf("PendingConfigMigrations() = %d, %v, %v, want the current version", version, migrations, err)
+	}
+
+	if backup, err := UpgradeConfig(path, config); err != nil || backup != "" {
+		t.Errorf("UpgradeConfig() of an up to date file = %s, %v, want no backup", backup, err)
+	}
+}
@@ -0,0 +1,305 @@
+package pkgmeta
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of the packager meta-data file in the root of an addon's repository.
+const FileName = ".pkgmeta"
+
+// Pkgmeta describes how the contents of an addon's repository are turned into a release, as used by the
+// BigWigs and CurseForge packagers.
+type Pkgmeta struct {
+	// PackageAs is the name of the directory the repository is packaged into.
+	PackageAs string `yaml:"package-as"`
+	// Ignore lists paths or glob patterns, relative to the repository, that are excluded from the package.
+	Ignore []string `yaml:"ignore"`
+	// MoveFolders maps paths within the package (starting with PackageAs) to the top-level directories they should be
+	// moved to.
+	MoveFolders map[string]string `yaml:"move-folders"`
+	// Externals maps paths within the package to other repositories that should be embedded at that path.
+	Externals map[string]External `yaml:"externals"`
+}
+
+// External is another repository that is embedded into the package.
+type External struct {
+	Url    string `yaml:"url"`
+	Type   string `yaml:"type"`
+	Tag    string `yaml:"tag"`
+	Branch string `yaml:"branch"`
+	Commit string `yaml:"commit"`
+}
+
+// UnmarshalYAML allows externals to be given either as a bare URL or as a map of options.
+func (e *External) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var url string
+	if err := unmarshal(&url); err == nil {
+		*e = External{Url: url}
+		return nil
+	}
+
+	type plain External
+	return unmarshal((*plain)(e))
+}
+
+// IsGit determines whether the external is a git repository, either because it says so or because its URL looks like
+// one.
+func (e External) IsGit() bool {
+	if e.Type != "" {
+		return strings.EqualFold(e.Type, "git")
+	}
+	return strings.HasSuffix(e.Url, ".git") || strings.HasPrefix(e.Url, "git://") || strings.Contains(e.Url, "github.com/") ||
+		strings.Contains(e.Url, "gitlab.com/")
+}
+
+// Ref returns the commit, branch or tag that should be used for the external, in that order of preference. "latest"
+// indicates the most recent tag should be used, and an empty string indicates the default branch.
+func (e External) Ref() string {
+	switch {
+	case e.Commit != "":
+		return e.Commit
+	case e.Branch != "":
+		return e.Branch
+	default:
+		return e.Tag
+	}
+}
+
+// Parse reads packager meta-data from the given reader.
+func Parse(r io.Reader) (*Pkgmeta, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Pkgmeta{}
+	if err := yaml.Unmarshal(b, meta); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", FileName, err)
+	}
+	return meta, nil
+}
+
+// Load reads the packager meta-data from the root of the given directory. If there is no meta-data file, nil is
+// returned without an error.
+func Load(dir string) (*Pkgmeta, error) {
+	f, err := os.Open(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Fetcher retrieves an external repository into the given directory.
+type Fetcher func(ctx context.Context, external External, target string) error
+
+// Options control how a repository is packaged.
+type Options struct {
+	// Name is the directory name to use if the meta-data doesn't specify one.
+	Name string
+	// Version replaces the @project-version@ keyword in source files.
+	Version string
+	// Fetch retrieves externals. If nil, packaging fails if there are any externals.
+	Fetch Fetcher
+}
+
+// keywordExtensions lists the types of file that keywords are replaced in.
+var keywordExtensions = map[string]bool{
+	".lua": true,
+	".toc": true,
+	".xml": true,
+	".txt": true,
+	".md":  true,
+}
+
+// Package builds an addon package from the source directory into the target directory, applying the packager
+// meta-data: ignored files are removed, keywords are substituted, externals are fetched and folders are moved. It
+// returns the names of the top-level directories in the package.
+func (p *Pkgmeta) Package(ctx context.Context, source string, target string, opts Options) ([]string, error) {
+	name := p.PackageAs
+	if name == "" {
+		name = opts.Name
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid package name: %q", name)
+	}
+
+	root := filepath.Join(target, name)
+	if err := p.copy(ctx, source, root); err != nil {
+		return nil, err
+	}
+
+	if err := SubstituteKeywords(root, opts.Version); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path := range p.Externals {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if opts.Fetch == nil {
+			return nil, fmt.Errorf("unable to fetch external %s", path)
+		}
+
+		dest, err := within(root, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.RemoveAll(dest); err != nil {
+			return nil, err
+		}
+
+		if err := opts.Fetch(ctx, p.Externals[path], dest); err != nil {
+			return nil, fmt.Errorf("unable to fetch external %s: %v", path, err)
+		}
+	}
+
+	var moves []string
+	for from := range p.MoveFolders {
+		moves = append(moves, from)
+	}
+	// Move the most deeply nested folders first, so moving their parents doesn't invalidate the paths
+	sort.Slice(moves, func(i, j int) bool {
+		return len(moves[i]) > len(moves[j])
+	})
+
+	for _, from := range moves {
+		src, err := within(target, from)
+		if err != nil {
+			return nil, err
+		}
+
+		dest, err := within(target, p.MoveFolders[from])
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.RemoveAll(dest); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), os.FileMode(0755)); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(src, dest); err != nil {
+			return nil, fmt.Errorf("unable to move folder %s: %v", from, err)
+		}
+	}
+
+	fs, err := ioutil.ReadDir(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, f := range fs {
+		if f.IsDir() {
+			dirs = append(dirs, f.Name())
+		}
+	}
+	return dirs, nil
+}
+
+// copy copies the source directory to the target, skipping hidden files and anything matching the ignore rules.
+func (p *Pkgmeta) copy(ctx context.Context, source string, target string) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return os.MkdirAll(target, os.FileMode(0755))
+		}
+
+		if strings.HasPrefix(info.Name(), ".") || p.ignored(filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		dest := filepath.Join(target, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dest, os.FileMode(0755))
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(dest, b, info.Mode().Perm())
+	})
+}
+
+// ignored determines whether the given slash-separated path matches any of the ignore rules.
+func (p *Pkgmeta) ignored(rel string) bool {
+	for _, pattern := range p.Ignore {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		if pattern == rel {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if matched, _ := filepath.Match(pattern, filepath.Base(rel)); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SubstituteKeywords replaces the @project-version@ keyword in all source files beneath the given directory.
+func SubstituteKeywords(dir string, version string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || !keywordExtensions[strings.ToLower(filepath.Ext(path))] {
+			return err
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		replaced := strings.ReplaceAll(string(b), "@project-version@", version)
+		if replaced == string(b) {
+			return nil
+		}
+		return ioutil.WriteFile(path, []byte(replaced), info.Mode().Perm())
+	})
+}
+
+// within joins the slash-separated path onto the base directory, returning an error if it would escape it.
+func within(base string, path string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if clean == "." || strings.HasPrefix(clean, "..") || filepath.IsAbs(clean) {
+		return "", fmt.Errorf("invalid path in %s: %s", FileName, path)
+	}
+	return filepath.Join(base, clean), nil
+}
@@ -0,0 +1,210 @@
+package wadman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/csmith/wadman/wow"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultType is the type of addon assumed when an ID has no prefix, or an old config file doesn't specify a type.
+const DefaultType = TypeCurseForge
+
+// Provider is a source of addons, such as CurseForge. Providers are responsible for creating addons from their IDs,
+// (de)serialising them, searching for them, and working out which file should be installed.
+type Provider interface {
+	// Type returns the type of addon the provider deals with. This is also the prefix used in addon IDs.
+	Type() AddonType
+	// Name returns a human-readable name for the provider.
+	Name() string
+
+	// NewAddon creates a new, uninstalled, addon from the source-specific part of its ID (i.e., without the prefix).
+	NewAddon(id string) (Addon, error)
+	// Marshal encodes an addon created by this provider to JSON.
+	Marshal(addon Addon) ([]byte, error)
+	// Unmarshal decodes an addon previously encoded with Marshal.
+	Unmarshal(data []byte) (Addon, error)
+
+	// Search finds addons matching the given query.
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+	// Resolve finds the release that should be installed for the given addon, taking into account its channel and
+	// the flavour of the install. The install may be nil, in which case providers should assume retail. Providers
+	// should send a CandidateEvent to the observer for each file they consider.
+	Resolve(ctx context.Context, addon Addon, w *wow.Install, observer Observer) (*Release, error)
+	// Details retrieves information about the addon and every file available for it.
+	Details(ctx context.Context, addon Addon) (*AddonDetails, error)
+	// Changelog retrieves the changelog for the given release of the addon, which may contain HTML. Providers should
+	// return an empty string if their source doesn't publish changelogs.
+	Changelog(ctx context.Context, addon Addon, release *Release) (string, error)
+}
+
+// AddonDetails describes an addon as reported by its source.
+type AddonDetails struct {
+	Name        string
+	Description string
+	Authors     []string
+	Website     string
+	Files       []File
+}
+
+// File is a downloadable file for an addon, along with the channel and flavour it belongs to.
+type File struct {
+	Release
+	Channel      Channel
+	Flavour      wow.Flavour
+	GameVersions []string
+}
+
+// Release describes a single downloadable file for an addon.
+type Release struct {
+	// FileId uniquely identifies the file within the addon's source.
+	FileId string
+	// Name is the name of the addon, as reported by its source.
+	Name string
+	// Version is the human-readable version of the file.
+	Version string
+	// Url is the address the file can be downloaded from.
+	Url string
+	// Checksum is the expected hex-encoded SHA-256 checksum of the file, if known.
+	Checksum string
+	// Date is the time the file was published, if known.
+	Date time.Time
+}
+
+var (
+	providersMutex sync.RWMutex
+	providers      = make(map[AddonType]Provider)
+)
+
+// RegisterProvider makes a provider available for use. It panics if a provider is already registered for the
+// same type.
+func RegisterProvider(provider Provider) {
+	providersMutex.Lock()
+	defer providersMutex.Unlock()
+
+	if _, ok := providers[provider.Type()]; ok {
+		panic(fmt.Sprintf("provider already registered for addon type %s", provider.Type()))
+	}
+	providers[provider.Type()] = provider
+}
+
+// Providers returns all registered providers, ordered by type.
+func Providers() []Provider {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+
+	var res []Provider
+	for _, p := range providers {
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Type() < res[j].Type()
+	})
+	return res
+}
+
+// ProviderFor returns the provider registered for the given type of addon.
+func ProviderFor(t AddonType) (Provider, error) {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+
+	if p, ok := providers[t]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown addon type: %s", t)
+}
+
+// ParseId splits an addon ID of the form "type:id" into its type and source-specific ID.
+func ParseId(id string) (AddonType, string, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		var suggestions []string
+		for _, p := range Providers() {
+			suggestions = append(suggestions, fmt.Sprintf("%s:%s", p.Type(), id))
+		}
+		return TypeUnspecified, "", fmt.Errorf("unrecognised addon type. Did you mean %s?", strings.Join(suggestions, " or "))
+	}
+	return AddonType(strings.ToLower(parts[0])), parts[1], nil
+}
+
+// NewAddon creates a new, uninstalled, addon from an ID of the form "type:id".
+func NewAddon(id string) (Addon, error) {
+	t, sourceId, err := ParseId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := ProviderFor(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.NewAddon(sourceId)
+}
+
+// FindRelease looks up a specific file of an addon, identified either by its file ID or its version. File IDs take
+// precedence over versions, and versions are compared case-insensitively.
+func FindRelease(ctx context.Context, addon Addon, fileIdOrVersion string) (*Release, error) {
+	provider, err := ProviderFor(addon.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := provider.Details(ctx, addon)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range details.Files {
+		if details.Files[i].FileId == fileIdOrVersion {
+			return &details.Files[i].Release, nil
+		}
+	}
+
+	var match *Release
+	for i := range details.Files {
+		if strings.EqualFold(details.Files[i].Version, fileIdOrVersion) {
+			if match != nil {
+				return nil, fmt.Errorf("version %s matches more than one file of %s; please specify a file ID", fileIdOrVersion, addon.ShortName())
+			}
+			match = &details.Files[i].Release
+		}
+	}
+
+	if match == nil && len(details.Files) == 1 {
+		// Some sources, such as WoW Interface, only offer the current file
+		return nil, fmt.Errorf("no file or version %s found for %s; only the current version (%s) is available", fileIdOrVersion, addon.ShortName(), details.Files[0].Version)
+	} else if match == nil {
+		return nil, fmt.Errorf("no file or version %s found for %s", fileIdOrVersion, addon.ShortName())
+	}
+	return match, nil
+}
+
+// marshalAddon encodes the given addon using its provider.
+func marshalAddon(addon Addon) (json.RawMessage, error) {
+	provider, err := ProviderFor(addon.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Marshal(addon)
+}
+
+// unmarshalAddon decodes an addon using the provider specified in its type field.
+func unmarshalAddon(data json.RawMessage) (Addon, error) {
+	base := BaseAddon{}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	provider, err := ProviderFor(base.AddonType)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Unmarshal(data)
+}
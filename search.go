@@ -0,0 +1,164 @@
+package wadman
+
+import (
+	"context"
+	"fmt"
+	"github.com/csmith/wadman/wow"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchResult is a single addon found by a provider's search.
+type SearchResult struct {
+	Id          string
+	Name        string
+	Source      string
+	Summary     string
+	Author      string
+	Downloads   int64
+	LastUpdated time.Time
+	Flavours    []wow.Flavour
+	Categories  []string
+}
+
+// SearchOptions restricts the results returned by Search.
+type SearchOptions struct {
+	// Sources limits the search to the given types of addon. If empty, all registered providers are searched.
+	Sources []AddonType
+	// Flavour limits the results to addons that support the given flavour of the game.
+	Flavour wow.Flavour
+	// Category limits the results to addons with a category containing the given text.
+	Category string
+}
+
+// SearchError is returned by Search when one or more providers failed.
+type SearchError struct {
+	Errors map[AddonType]error
+}
+
+func (s *SearchError) Error() string {
+	var parts []string
+	for t, err := range s.Errors {
+		parts = append(parts, fmt.Sprintf("%s: %v", t, err))
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("search failed for some sources: %s", strings.Join(parts, "; "))
+}
+
+// Search queries all of the selected providers concurrently, and returns the combined results ranked by how closely
+// their names match the query and then by popularity. If some providers fail, the results from the others are
+// returned along with a *SearchError.
+func Search(ctx context.Context, query string, options SearchOptions) ([]SearchResult, error) {
+	var selected []Provider
+	for _, p := range Providers() {
+		if len(options.Sources) == 0 || containsType(options.Sources, p.Type()) {
+			selected = append(selected, p)
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		results []SearchResult
+		errs    = make(map[AddonType]error)
+	)
+
+	for _, p := range selected {
+		wg.Add(1)
+		go func(p Provider) {
+			defer wg.Done()
+			res, err := p.Search(ctx, query)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs[p.Type()] = err
+			}
+			for i := range res {
+				if options.matches(&res[i]) {
+					results = append(results, res[i])
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	sortResults(strings.ToLower(query), results)
+
+	if len(errs) > 0 {
+		return results, &SearchError{Errors: errs}
+	}
+	return results, nil
+}
+
+// sortResults orders the results with the most relevant first. Providers are searched concurrently so the results
+// arrive in no particular order; ties are broken by source, name and ID so the order is the same every time.
+func sortResults(query string, results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		if sa, sb := relevance(query, a.Name), relevance(query, b.Name); sa != sb {
+			return sa > sb
+		}
+		if a.Downloads != b.Downloads {
+			return a.Downloads > b.Downloads
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if na, nb := strings.ToLower(a.Name), strings.ToLower(b.Name); na != nb {
+			return na < nb
+		}
+		return a.Id < b.Id
+	})
+}
+
+// matches determines whether the given result satisfies the flavour and category filters.
+func (o SearchOptions) matches(result *SearchResult) bool {
+	if o.Flavour != wow.FlavourUnknown {
+		found := false
+		for _, f := range result.Flavours {
+			found = found || f == o.Flavour
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if o.Category != "" {
+		found := false
+		for _, c := range result.Categories {
+			found = found || strings.Contains(strings.ToLower(c), strings.ToLower(o.Category))
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// relevance scores how closely the name matches the (lower-cased) query.
+func relevance(query string, name string) int {
+	name = strings.ToLower(name)
+	switch {
+	case name == query:
+		return 3
+	case strings.HasPrefix(name, query):
+		return 2
+	case strings.Contains(name, query):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func containsType(types []AddonType, t AddonType) bool {
+	for i := range types {
+		if types[i] == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package wadman
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSortResults(t *testing.T) {
+	want := []SearchResult{
+		{Id: "curse:3358", Name: "Deadly Boss Mods", Source: "CurseForge", Downloads: 100},
+		{Id: "wowi:8814", Name: "Deadly Boss Mods", Source: "WoW Interface", Downloads: 100},
+		{Id: "curse:2", Name: "Deadly Boss Mods - Dungeons", Source: "CurseForge", Downloads: 50},
+		{Id: "curse:3", Name: "deadly boss mods - raids", Source: "CurseForge", Downloads: 50},
+		{Id: "wago:a", Name: "Deadly Boss Mods - PvP", Source: "Wago Addons", Downloads: 50},
+		{Id: "wago:b", Name: "Deadly Boss Mods - PvP", Source: "Wago Addons", Downloads: 50},
+		{Id: "curse:4", Name: "Not Deadly Boss Mods", Source: "CurseForge", Downloads: 1000},
+		{Id: "curse:5", Name: "Boss Timers", Source: "CurseForge", Downloads: 5000},
+	}
+	for i := 0; i < 20; i++ {
+		results := append([]SearchResult(nil), want...)
+		rand.Shuffle(len(results), func(i, j int) {
+			results[i], results[j] = results[j], results[i]
+		})
+
+		sortResults("deadly boss mods", results)
+		if !reflect.DeepEqual(results, want) {
+			t.Fatalf("sortResults() = %v, want %v", results, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package wadman
+
+// SyncPlan describes the changes required to make a set of installed addons match an addon list.
+type SyncPlan struct {
+	// Install contains entries for addons that are in the list but not installed.
+	Install []AddonListEntry
+	// Remove contains installed addons that are not in the list. Addons installed from local files or directories are
+	// never removed, as they can't be included in a portable list.
+	Remove []Addon
+	// Change contains installed addons whose channel or pin differs from the list.
+	Change []SyncChange
+}
+
+// SyncChange describes an installed addon whose settings differ from its entry in an addon list.
+type SyncChange struct {
+	Addon Addon
+	Entry AddonListEntry
+}
+
+// ChannelChanged determines whether the addon's channel differs from the list.
+func (c SyncChange) ChannelChanged() bool {
+	return c.Addon.Channel() != c.Entry.Channel
+}
+
+// PinChanged determines whether the addon's pin differs from the list.
+func (c SyncChange) PinChanged() bool {
+	return c.Addon.Pin() != c.Entry.Pin
+}
+
+// Empty determines whether the plan requires any changes at all.
+func (p *SyncPlan) Empty() bool {
+	return len(p.Install) == 0 && len(p.Remove) == 0 && len(p.Change) == 0
+}
+
+// Plan works out the changes needed to make the given installed addons match the list.
+func (l *AddonList) Plan(addons []Addon) *SyncPlan {
+	plan := &SyncPlan{}
+
+	installed := make(map[string]Addon)
+	for i := range addons {
+		installed[addons[i].ShortName()] = addons[i]
+	}
+
+	wanted := make(map[string]bool)
+	for _, entry := range l.Addons {
+		if wanted[entry.Id] {
+			continue
+		}
+
+		wanted[entry.Id] = true
+		if addon, ok := installed[entry.Id]; !ok {
+			plan.Install = append(plan.Install, entry)
+		} else if change := (SyncChange{Addon: addon, Entry: entry}); change.ChannelChanged() || change.PinChanged() {
+			plan.Change = append(plan.Change, change)
+		}
+	}
+
+	for i := range addons {
+		if t := addons[i].Type(); t == TypeFile || t == TypeDirectory {
+			continue
+		}
+
+		if !wanted[addons[i].ShortName()] {
+			plan.Remove = append(plan.Remove, addons[i])
+		}
+	}
+
+	return plan
+}
@@ -0,0 +1,101 @@
+package wadman
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	release := NewCurseForgeAddon(3358)
+	release.SetChannel(ChannelRelease)
+	pinned := NewWowInterfaceAddon(15749)
+	pinned.SetPin("a1b2c3")
+	unwanted := NewWagoAddon("aNDmy96o")
+
+	list := &AddonList{Addons: []AddonListEntry{
+		{Id: "curse:3358", Channel: ChannelBeta},
+		{Id: "wowi:15749"},
+		{Id: "curse:1234"},
+		{Id: "curse:1234"},
+	}}
+
+	plan := list.Plan([]Addon{release, pinned, unwanted, NewDirectoryAddon("/addons/Guild")})
+
+	if want := []AddonListEntry{{Id: "curse:1234"}}; !reflect.DeepEqual(plan.Install, want) {
+		t.Errorf("Install = %v, want %v", plan.Install, want)
+	}
+
+	if want := []Addon{unwanted}; !reflect.DeepEqual(plan.Remove, want) {
+		t.Errorf("Remove = %v, want %v", plan.Remove, want)
+	}
+
+	if len(plan.Change) != 2 {
+		t.Fatalf("Change = %v, want changes for curse:3358 and wowi:15749", plan.Change)
+	}
+
+	if c := plan.Change[0]; c.Addon != release || !c.ChannelChanged() || c.PinChanged() {
+		t.Errorf("Change[0] = %v, want a channel change for curse:3358", c)
+	}
+
+	if c := plan.Change[1]; c.Addon != pinned || c.ChannelChanged() || !c.PinChanged() {
+		t.Errorf("Change[1] = %v, want a pin change for wowi:15749", c)
+	}
+}
+
+func TestPlan_ExportedList(t *testing.T) {
+	beta := NewCurseForgeAddon(3358)
+	beta.SetChannel(ChannelBeta)
+	pinned := NewWowInterfaceAddon(15749)
+	pinned.SetPin("a1b2c3")
+	addons := []Addon{
+		beta,
+		pinned,
+		NewWagoAddon("aNDmy96o"),
+		NewFileAddon("/downloads/MyAddon.zip"),
+		NewDirectoryAddon("/addons/Guild"),
+	}
+
+	list, skipped := NewAddonList(addons)
+	if len(skipped) != 2 {
+		t.Errorf("NewAddonList() skipped %v, want the local addons", skipped)
+	}
+
+	if plan := list.Plan(addons); !plan.Empty() {
+		t.Errorf("Plan() of an exported list = %+v, want no changes", plan)
+	}
+}
+
+func TestPlan_ReadAddonList(t *testing.T) {
+	beta := NewCurseForgeAddon(3358)
+	beta.SetChannel(ChannelBeta)
+	addons := []Addon{beta, NewWowInterfaceAddon(15749)}
+
+	list, err := ReadAddonList(strings.NewReader(`{"addons": [
+		{"id": "Curse:3358", "channel": "Beta"},
+		{"id": "WOWI:15749"}
+	]}`))
+	if err != nil {
+		t.Fatalf("ReadAddonList() failed: %v", err)
+	}
+
+	want := []AddonListEntry{{Id: "curse:3358", Channel: ChannelBeta}, {Id: "wowi:15749"}}
+	if !reflect.DeepEqual(list.Addons, want) {
+		t.Errorf("ReadAddonList() = %v, want %v", list.Addons, want)
+	}
+
+	if plan := list.Plan(addons); !plan.Empty() {
+		t.Errorf("Plan() = %+v, want no changes", plan)
+	}
+
+	invalid := []string{
+		`{"addons": [{"id": "curse:3358", "channel": "nightly"}]}`,
+		`{"addons": [{"id": "curse:dbm"}]}`,
+		`{"addons": [{"id": "3358"}]}`,
+	}
+	for _, input := range invalid {
+		if list, err := ReadAddonList(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadAddonList(%s) = %v, want error", input, list.Addons)
+		}
+	}
+}
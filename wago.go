@@ -0,0 +1,301 @@
+package wadman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/csmith/wadman/wago"
+	"github.com/csmith/wadman/wow"
+	"strings"
+)
+
+func init() {
+	RegisterProvider(&wagoProvider{})
+}
+
+// WagoClient is used to access the Wago Addons API. Its API key must be set before wago addons can be installed or
+// updated, and its base URL may be changed to use a different server.
+var WagoClient = wago.NewClient("")
+
+type WagoAddon struct {
+	BaseAddon
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	ReleaseId string `json:"release_id"`
+}
+
+func NewWagoAddon(id string) Addon {
+	return &WagoAddon{BaseAddon: BaseAddon{AddonType: TypeWago}, Id: id}
+}
+
+func (w *WagoAddon) DisplayName() string {
+	return w.Name
+}
+
+func (w *WagoAddon) ShortName() string {
+	return fmt.Sprintf("wago:%s", w.Id)
+}
+
+func (w *WagoAddon) Locked() LockEntry {
+	return w.LockEntry(w.ShortName(), w.Name, w.ReleaseId)
+}
+
+// Install downloads the release using WagoClient, as Wago requires downloads to be authenticated. The API key is only
+// sent if the download is served by the API itself.
+func (w *WagoAddon) Install(ctx context.Context, install *wow.Install, release *Release, progress wow.ProgressFunc) error {
+	if release.Url == "" {
+		return fmt.Errorf("no download address for file %s", release.FileId)
+	}
+
+	req, err := WagoClient.NewRequest(ctx, release.Url)
+	if err != nil {
+		return err
+	}
+
+	b, checksum, err := wow.DownloadRequest(WagoClient.Http, req, release.Checksum, progress)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := install.ReplaceAddons(ctx, bytes.NewReader(b), w.Directories)
+	if err != nil {
+		return err
+	}
+
+	w.deployed(release, dirs, checksum)
+	w.ReleaseId = release.FileId
+	if release.Name != "" {
+		w.Name = release.Name
+	}
+	return nil
+}
+
+type wagoProvider struct{}
+
+func (p *wagoProvider) Type() AddonType {
+	return TypeWago
+}
+
+func (p *wagoProvider) Name() string {
+	return "Wago Addons"
+}
+
+func (p *wagoProvider) NewAddon(id string) (Addon, error) {
+	if id == "" || strings.ContainsAny(id, "/?#") {
+		return nil, fmt.Errorf("invalid Wago Addons project ID: %s", id)
+	}
+	return NewWagoAddon(id), nil
+}
+
+func (p *wagoProvider) Marshal(addon Addon) ([]byte, error) {
+	return json.Marshal(addon)
+}
+
+func (p *wagoProvider) Unmarshal(data []byte) (Addon, error) {
+	addon := NewWagoAddon("")
+	if err := json.Unmarshal(data, addon); err != nil {
+		return nil, err
+	}
+	return addon, nil
+}
+
+// Search finds addons on Wago Addons. As the API can't be used without a key, no results are returned if one hasn't
+// been configured.
+func (p *wagoProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	if WagoClient.ApiKey == "" {
+		return nil, nil
+	}
+
+	addons, err := WagoClient.SearchAddons(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, a := range addons {
+		result := SearchResult{
+			Id:         fmt.Sprintf("wago:%s", a.Id),
+			Name:       a.DisplayName,
+			Source:     p.Name(),
+			Summary:    a.Summary,
+			Author:     strings.Join(a.Authors, ", "),
+			Downloads:  a.Downloads,
+			Categories: a.Categories,
+		}
+
+		for _, v := range a.GameVersions {
+			if flavour := wagoFlavour(v); flavour != wow.FlavourUnknown {
+				result.Flavours = append(result.Flavours, flavour)
+			}
+		}
+
+		for _, r := range a.RecentRelease {
+			if r != nil && r.CreatedAt.After(result.LastUpdated) {
+				result.LastUpdated = r.CreatedAt
+			}
+		}
+
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Resolve picks the newest release for the install's flavour that is at least as stable as the addon's channel
+// allows.
+func (p *wagoProvider) Resolve(ctx context.Context, addon Addon, install *wow.Install, observer Observer) (*Release, error) {
+	w, ok := addon.(*WagoAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a Wago Addons addon", addon.ShortName())
+	}
+
+	flavour := wow.FlavourRetail
+	if install != nil {
+		flavour = install.Flavour()
+	}
+
+	details, err := WagoClient.GetAddon(ctx, w.Id, wagoGameVersion(flavour))
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := wagoTypes(w.UpdateChannel)
+	var best *wago.Release
+	for _, t := range []string{wago.Stable, wago.Beta, wago.Alpha} {
+		r := details.RecentRelease[t]
+		if r != nil && allowed[t] && (best == nil || r.CreatedAt.After(best.CreatedAt)) {
+			best = r
+		}
+	}
+
+	for _, t := range []string{wago.Stable, wago.Beta, wago.Alpha} {
+		if r := details.RecentRelease[t]; r != nil {
+			observer.Notify(CandidateEvent{
+				Addon:    addon,
+				FileId:   r.Id,
+				Version:  r.Label,
+				Date:     r.CreatedAt,
+				Eligible: allowed[t],
+				Selected: r == best,
+
+				Flavour:           flavour,
+				FlavourValid:      true,
+				Channel:           wagoChannel(t),
+				ChannelValid:      allowed[t],
+				GameVersionsValid: true,
+			})
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no releases found for addon %s (%s)", w.Id, details.DisplayName)
+	}
+
+	return &Release{
+		FileId:  best.Id,
+		Name:    details.DisplayName,
+		Version: best.Label,
+		Url:     best.DownloadLink,
+		Date:    best.CreatedAt,
+	}, nil
+}
+
+// Details retrieves the latest release of each type for every flavour of the game.
+func (p *wagoProvider) Details(ctx context.Context, addon Addon) (*AddonDetails, error) {
+	w, ok := addon.(*WagoAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a Wago Addons addon", addon.ShortName())
+	}
+
+	details := &AddonDetails{}
+	for _, version := range []string{wago.Retail, wago.Classic, wago.Bcc, wago.Wrath} {
+		info, err := WagoClient.GetAddon(ctx, w.Id, version)
+		if err != nil {
+			return nil, err
+		}
+
+		details.Name = info.DisplayName
+		details.Description = info.Summary
+		details.Authors = info.Authors
+		details.Website = info.WebsiteUrl
+
+		for _, t := range []string{wago.Stable, wago.Beta, wago.Alpha} {
+			r := info.RecentRelease[t]
+			if r == nil {
+				continue
+			}
+
+			details.Files = append(details.Files, File{
+				Release: Release{
+					FileId:  r.Id,
+					Name:    info.DisplayName,
+					Version: r.Label,
+					Url:     r.DownloadLink,
+					Date:    r.CreatedAt,
+				},
+				Channel:      wagoChannel(t),
+				Flavour:      wagoFlavour(version),
+				GameVersions: r.Patches,
+			})
+		}
+	}
+	return details, nil
+}
+
+func (p *wagoProvider) Changelog(context.Context, Addon, *Release) (string, error) {
+	return "", nil
+}
+
+// wagoTypes returns the Wago release types that may be installed for the given channel.
+func wagoTypes(channel Channel) map[string]bool {
+	switch channel {
+	case ChannelRelease:
+		return map[string]bool{wago.Stable: true}
+	case ChannelAlpha:
+		return map[string]bool{wago.Stable: true, wago.Beta: true, wago.Alpha: true}
+	default:
+		return map[string]bool{wago.Stable: true, wago.Beta: true}
+	}
+}
+
+// wagoChannel converts a Wago release type into a Channel.
+func wagoChannel(t string) Channel {
+	switch t {
+	case wago.Stable:
+		return ChannelRelease
+	case wago.Beta:
+		return ChannelBeta
+	default:
+		return ChannelAlpha
+	}
+}
+
+// wagoFlavour converts a Wago game version into a wow.Flavour.
+func wagoFlavour(version string) wow.Flavour {
+	switch version {
+	case wago.Retail:
+		return wow.FlavourRetail
+	case wago.Classic:
+		return wow.FlavourClassic
+	case wago.Bcc:
+		return wow.FlavourBurningCrusade
+	case wago.Wrath:
+		return wow.FlavourWrath
+	default:
+		return wow.FlavourUnknown
+	}
+}
+
+// wagoGameVersion converts a wow.Flavour into a Wago game version.
+func wagoGameVersion(flavour wow.Flavour) string {
+	switch flavour {
+	case wow.FlavourClassic:
+		return wago.Classic
+	case wow.FlavourBurningCrusade:
+		return wago.Bcc
+	case wow.FlavourWrath:
+		return wago.Wrath
+	default:
+		return wago.Retail
+	}
+}
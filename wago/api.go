@@ -0,0 +1,148 @@
+package wago
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseUrl is the address of the Wago Addons API.
+const DefaultBaseUrl = "https://addons.wago.io"
+
+// ErrUnauthorized is returned when the API rejects the client's API key, or no key was given.
+var ErrUnauthorized = errors.New("missing or invalid Wago API key")
+
+// Release types, from most to least stable.
+const (
+	Stable = "stable"
+	Beta   = "beta"
+	Alpha  = "alpha"
+)
+
+// Game versions that releases may be published for.
+const (
+	Retail  = "retail"
+	Classic = "classic"
+	Bcc     = "bc"
+	Wrath   = "wotlk"
+)
+
+type Release struct {
+	Id           string    `json:"id"`
+	Label        string    `json:"label"`
+	DownloadLink string    `json:"download_link"`
+	CreatedAt    time.Time `json:"created_at"`
+	Patches      []string  `json:"patches"`
+}
+
+type Addon struct {
+	Id            string              `json:"id"`
+	Slug          string              `json:"slug"`
+	DisplayName   string              `json:"display_name"`
+	Summary       string              `json:"summary"`
+	WebsiteUrl    string              `json:"website_url"`
+	Authors       []string            `json:"authors"`
+	Categories    []string            `json:"categories"`
+	Downloads     int64               `json:"download_count"`
+	GameVersions  []string            `json:"game_versions"`
+	RecentRelease map[string]*Release `json:"recent_release"`
+}
+
+// Client accesses the Wago Addons API.
+type Client struct {
+	// BaseUrl is the address of the API, without a trailing slash.
+	BaseUrl string
+	// ApiKey authenticates requests to the API.
+	ApiKey string
+	// Http is the client used to make requests.
+	Http *http.Client
+}
+
+// NewClient creates a client for the public Wago Addons API using the given API key.
+func NewClient(apiKey string) *Client {
+	return &Client{BaseUrl: DefaultBaseUrl, ApiKey: apiKey, Http: http.DefaultClient}
+}
+
+// GetAddon retrieves the details of an addon, including its most recent release of each type for the given game
+// version.
+func (c *Client) GetAddon(ctx context.Context, id string, gameVersion string) (*Addon, error) {
+	addon := &Addon{}
+	path := fmt.Sprintf("/api/external/addons/%s?game_version=%s", url.PathEscape(id), url.QueryEscape(gameVersion))
+	if err := c.get(ctx, path, addon); err != nil {
+		return nil, err
+	}
+	return addon, nil
+}
+
+// SearchAddons finds addons matching the given query.
+func (c *Client) SearchAddons(ctx context.Context, query string) ([]*Addon, error) {
+	response := &struct {
+		Data []*Addon `json:"data"`
+	}{}
+	path := fmt.Sprintf("/api/external/addons/_search?query=%s", url.QueryEscape(query))
+	if err := c.get(ctx, path, response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// NewRequest creates a request for the given URL, which may be relative to the base URL. The API key is only added
+// if the URL is on the same host as the base URL, so that it isn't sent to other servers that downloads are hosted on.
+func (c *Client) NewRequest(ctx context.Context, target string) (*http.Request, error) {
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimSuffix(c.BaseUrl, "/") + target
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ApiKey != "" && c.isApiHost(req.URL) {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.ApiKey))
+	}
+	return req, nil
+}
+
+// isApiHost determines whether the given URL uses the same scheme and host as the base URL.
+func (c *Client) isApiHost(u *url.URL) bool {
+	base, err := url.Parse(c.BaseUrl)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(base.Scheme, u.Scheme) && strings.EqualFold(base.Host, u.Host)
+}
+
+// get performs an authenticated GET request for the given path and decodes the JSON response into target.
+func (c *Client) get(ctx context.Context, path string, target interface{}) error {
+	req, err := c.NewRequest(ctx, path)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := c.Http
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(res.Body).Decode(target)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	default:
+		return fmt.Errorf("wago API request failed: %s", res.Status)
+	}
+}
@@ -0,0 +1,114 @@
+package wago
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetAddon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if accept := r.Header.Get("Accept"); accept != "application/json" {
+			t.Errorf("API request Accept = %q, want application/json", accept)
+		}
+
+		if r.URL.Path != "/api/external/addons/abc def" || r.URL.Query().Get("game_version") != Classic {
+			t.Errorf("unexpected request for %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":           "abc def",
+			"display_name": "Test Addon",
+			"recent_release": map[string]interface{}{
+				Stable: map[string]interface{}{"id": "r1", "label": "1.0", "download_link": "https://example.com/1.zip"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("secret")
+	client.BaseUrl = server.URL
+	client.Http = server.Client()
+
+	addon, err := client.GetAddon(context.Background(), "abc def", Classic)
+	if err != nil {
+		t.Fatalf("GetAddon() failed: %v", err)
+	}
+
+	if addon.DisplayName != "Test Addon" || addon.RecentRelease[Stable] == nil || addon.RecentRelease[Stable].Label != "1.0" {
+		t.Errorf("GetAddon() = %+v", addon)
+	}
+
+	client.ApiKey = "wrong"
+	if _, err := client.GetAddon(context.Background(), "abc def", Classic); err != ErrUnauthorized {
+		t.Errorf("GetAddon() with a bad key returned %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClient_SearchAddons(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/external/addons/_search" || r.URL.Query().Get("query") != "deadly boss" {
+			t.Errorf("unexpected request for %s", r.URL)
+		}
+
+		_, _ = w.Write([]byte(`{"data":[{"id":"a","display_name":"A"},{"id":"b","display_name":"B"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("secret")
+	client.BaseUrl = server.URL
+
+	addons, err := client.SearchAddons(context.Background(), "deadly boss")
+	if err != nil {
+		t.Fatalf("SearchAddons() failed: %v", err)
+	}
+
+	if len(addons) != 2 || addons[0].Id != "a" || addons[1].DisplayName != "B" {
+		t.Errorf("SearchAddons() = %+v", addons)
+	}
+}
+
+func TestClient_NewRequest(t *testing.T) {
+	client := NewClient("secret")
+	client.BaseUrl = "https://addons.example.com"
+
+	tests := []struct {
+		target   string
+		url      string
+		withAuth bool
+	}{
+		{target: "/api/thing", url: "https://addons.example.com/api/thing", withAuth: true},
+		{target: "https://addons.example.com/download/1.zip", url: "https://addons.example.com/download/1.zip", withAuth: true},
+		{target: "https://ADDONS.example.com/download/1.zip", url: "https://ADDONS.example.com/download/1.zip", withAuth: true},
+		{target: "https://cdn.example.com/download/1.zip", url: "https://cdn.example.com/download/1.zip"},
+		{target: "https://addons.example.com.evil.test/1.zip", url: "https://addons.example.com.evil.test/1.zip"},
+		{target: "http://addons.example.com/download/1.zip", url: "http://addons.example.com/download/1.zip"},
+	}
+	for _, tt := range tests {
+		req, err := client.NewRequest(context.Background(), tt.target)
+		if err != nil {
+			t.Fatalf("NewRequest(%s) failed: %v", tt.target, err)
+		}
+
+		if req.URL.String() != tt.url {
+			t.Errorf("NewRequest(%s) requested %s, want %s", tt.target, req.URL, tt.url)
+		}
+
+		if auth := req.Header.Get("Authorization"); (auth != "") != tt.withAuth {
+			t.Errorf("NewRequest(%s) Authorization = %q, want it set: %t", tt.target, auth, tt.withAuth)
+		}
+
+		if accept := req.Header.Get("Accept"); accept != "" {
+			t.Errorf("NewRequest(%s) Accept = %q, want it unset so downloads aren't refused", tt.target, accept)
+		}
+	}
+}
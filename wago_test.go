@@ -0,0 +1,134 @@
+package wadman
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csmith/wadman/wago"
+	"github.com/csmith/wadman/wow"
+)
+
+// useWagoServer points WagoClient at a test server that serves releases for each game version, until the test ends.
+func useWagoServer(t *testing.T, releases map[string]map[string]*wago.Release) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&wago.Addon{
+			Id:            "abc",
+			DisplayName:   "Test Addon",
+			RecentRelease: releases[r.URL.Query().Get("game_version")],
+		})
+	}))
+
+	original := WagoClient
+	WagoClient = wago.NewClient("secret")
+	WagoClient.BaseUrl = server.URL
+	WagoClient.Http = server.Client()
+
+	t.Cleanup(func() {
+		WagoClient = original
+		server.Close()
+	})
+}
+
+func TestWagoProvider_Resolve(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2022, time.November, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	useWagoServer(t, map[string]map[string]*wago.Release{
+		wago.Retail: {
+			wago.Stable: {Id: "retail-stable", Label: "1.0", CreatedAt: day(1)},
+			wago.Beta:   {Id: "retail-beta", Label: "1.1-beta", CreatedAt: day(2)},
+			wago.Alpha:  {Id: "retail-alpha", Label: "1.2-alpha", CreatedAt: day(3)},
+		},
+		wago.Classic: {
+			wago.Stable: {Id: "classic-stable", Label: "1.0-classic", CreatedAt: day(1)},
+		},
+		wago.Wrath: {
+			wago.Stable: {Id: "wrath-stable", Label: "1.0-wrath", CreatedAt: day(1)},
+			wago.Beta:   {Id: "wrath-beta", Label: "1.1-wrath", CreatedAt: day(2)},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		install *wow.Install
+		channel Channel
+		want    string
+	}{
+		{name: "no install", channel: ChannelRelease, want: "retail-stable"},
+		{name: "retail release", install: wow.NewWowInstall(filepath.Join("wow", "_retail_")), channel: ChannelRelease, want: "retail-stable"},
+		{name: "retail beta", install: wow.NewWowInstall(filepath.Join("wow", "_retail_")), channel: ChannelBeta, want: "retail-beta"},
+		{name: "retail alpha", install: wow.NewWowInstall(filepath.Join("wow", "_retail_")), channel: ChannelAlpha, want: "retail-alpha"},
+		{name: "classic era", install: wow.NewWowInstall(filepath.Join("wow", "_classic_era_")), channel: ChannelAlpha, want: "classic-stable"},
+		{name: "wrath", install: wow.NewWowInstall(filepath.Join("wow", "_classic_")), channel: ChannelBeta, want: "wrath-beta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addon := NewWagoAddon("abc").(*WagoAddon)
+			addon.UpdateChannel = tt.channel
+
+			var candidates []CandidateEvent
+			release, err := (&wagoProvider{}).Resolve(context.Background(), addon, tt.install, func(event Event) {
+				if c, ok := event.(CandidateEvent); ok {
+					candidates = append(candidates, c)
+				}
+			})
+			if err != nil {
+				t.Fatalf("Resolve() failed: %v", err)
+			}
+
+			if release.FileId != tt.want || release.Name != "Test Addon" {
+				t.Errorf("Resolve() = %+v, want file %s", release, tt.want)
+			}
+
+			flavour := wow.FlavourRetail
+			if tt.install != nil {
+				flavour = tt.install.Flavour()
+			}
+
+			selected := 0
+			for _, c := range candidates {
+				if c.Flavour != flavour {
+					t.Errorf("candidate %s has flavour %s, want %s", c.FileId, c.Flavour, flavour)
+				}
+				if c.Selected {
+					selected++
+				}
+			}
+			if selected != 1 {
+				t.Errorf("%d candidates were selected, want 1", selected)
+			}
+		})
+	}
+}
+
+func TestWagoProvider_ResolveWithoutReleases(t *testing.T) {
+	useWagoServer(t, nil)
+
+	_, err := (&wagoProvider{}).Resolve(context.Background(), NewWagoAddon("abc"), nil, nil)
+	if err == nil {
+		t.Errorf("Resolve() succeeded for an addon without any releases")
+	}
+}
+
+func TestWagoProvider_ResolveWithoutKey(t *testing.T) {
+	useWagoServer(t, nil)
+	WagoClient.ApiKey = ""
+
+	_, err := (&wagoProvider{}).Resolve(context.Background(), NewWagoAddon("abc"), nil, nil)
+	if err != wago.ErrUnauthorized {
+		t.Errorf("Resolve() without a key returned %v, want wago.ErrUnauthorized", err)
+	}
+}
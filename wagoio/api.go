@@ -0,0 +1,122 @@
+package wagoio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseUrl is the address of the Wago.io data API.
+const DefaultBaseUrl = "https://data.wago.io"
+
+// Types of import string that can be checked for updates.
+const (
+	WeakAuras = "weakauras"
+	Plater    = "plater"
+)
+
+type Changelog struct {
+	Text   string `json:"text"`
+	Format string `json:"format"`
+}
+
+// Import is the latest version of an import string published on Wago.io.
+type Import struct {
+	Id            string    `json:"_id"`
+	Slug          string    `json:"slug"`
+	Name          string    `json:"name"`
+	Username      string    `json:"username"`
+	Version       int64     `json:"version"`
+	VersionString string    `json:"versionString"`
+	Changelog     Changelog `json:"changelog"`
+}
+
+// Client accesses the Wago.io data API.
+type Client struct {
+	// BaseUrl is the address of the API, without a trailing slash.
+	BaseUrl string
+	// ApiKey authenticates requests to the API. It is optional, but is required to access private imports.
+	ApiKey string
+	// Http is the client used to make requests.
+	Http *http.Client
+}
+
+// NewClient creates a client for the public Wago.io data API using the given API key, which may be blank.
+func NewClient(apiKey string) *Client {
+	return &Client{BaseUrl: DefaultBaseUrl, ApiKey: apiKey, Http: http.DefaultClient}
+}
+
+// Check retrieves the latest version of each of the imports with the given slugs or IDs. Imports that don't exist or
+// aren't accessible are omitted from the result.
+func (c *Client) Check(ctx context.Context, kind string, ids []string) ([]*Import, error) {
+	body, err := json.Marshal(&struct {
+		Ids []string `json:"ids"`
+	}{ids})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/check/%s", url.PathEscape(kind)), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	var imports []*Import
+	if err := json.NewDecoder(res.Body).Decode(&imports); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// Encoded retrieves the import string for the latest version of the import with the given ID.
+func (c *Client) Encoded(ctx context.Context, id string) (string, error) {
+	res, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/raw/encoded?id=%s", url.QueryEscape(id)), nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// do performs a request for the given path, returning an error if it wasn't successful.
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseUrl, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ApiKey != "" {
+		req.Header.Set("api-key", c.ApiKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.Http
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("wago.io API request failed: %s", res.Status)
+	}
+	return res, nil
+}
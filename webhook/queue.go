@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// maxAttempts is the number of times a delivery is attempted before it is given up on.
+	maxAttempts = 8
+	// initialRetryDelay is the time before a failed delivery is retried. It doubles after each failed attempt.
+	initialRetryDelay = time.Minute
+	// maxRetryDelay caps the time between attempts.
+	maxRetryDelay = 6 * time.Hour
+	// requestTimeout stops an unresponsive webhook from holding up updates.
+	requestTimeout = 30 * time.Second
+)
+
+var defaultClient = &http.Client{Timeout: requestTimeout}
+
+// Delivery is a notification waiting to be sent to a webhook.
+type Delivery struct {
+	Url         string          `json:"url"`
+	Body        json.RawMessage `json:"body"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Queue holds notifications until they have been delivered, so that they can be retried by later runs of wadman if
+// a webhook is unavailable.
+type Queue struct {
+	Deliveries []*Delivery
+	// Http is the client used to send notifications. A client with a short timeout is used if it is nil.
+	Http *http.Client
+	path string
+}
+
+// permanentError indicates a delivery was rejected and retrying it won't help.
+type permanentError struct {
+	error
+}
+
+// LoadQueue reads the queue saved at the given path. An empty queue is returned if the file doesn't exist.
+func LoadQueue(path string) (*Queue, error) {
+	queue := &Queue{path: path}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return queue, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &queue.Deliveries); err != nil {
+		return nil, fmt.Errorf("unable to parse webhook queue %s: %v", path, err)
+	}
+	return queue, nil
+}
+
+// Save writes the queue back to the file it was loaded from. The file is removed if the queue is empty. The queue is
+// written to a temporary file first, so the existing file is left untouched if anything goes wrong.
+func (q *Queue) Save() error {
+	if len(q.Deliveries) == 0 {
+		if err := os.Remove(q.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(q.Deliveries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Temporary files are only readable by the current user, which suits the queue as webhook URLs contain secrets
+	f, err := ioutil.TempFile(filepath.Dir(q.path), fmt.Sprintf(".%s-*", filepath.Base(q.path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), q.path)
+}
+
+// Add queues a notification of the summary for the given target.
+func (q *Queue) Add(target Target, summary *Summary) error {
+	body, err := target.Payload(summary)
+	if err != nil {
+		return fmt.Errorf("unable to build payload for %s: %v", target.Url, err)
+	}
+
+	q.Deliveries = append(q.Deliveries, &Delivery{Url: target.Url, Body: body, Created: summary.Time})
+	return nil
+}
+
+// Flush attempts to send every delivery that is due, returning the number that were sent and an error for each one
+// that failed. Deliveries that fail are kept for a later attempt unless they were rejected outright or have run out
+// of attempts. The queue is not saved automatically.
+func (q *Queue) Flush(ctx context.Context) (int, []error) {
+	var remaining []*Delivery
+	var errs []error
+	sent := 0
+	now := time.Now()
+	for _, d := range q.Deliveries {
+		if ctx.Err() != nil || d.NextAttempt.After(now) {
+			remaining = append(remaining, d)
+			continue
+		}
+
+		err := q.Send(ctx, d.Url, d.Body)
+		if err == nil {
+			sent++
+			continue
+		}
+
+		d.Attempts++
+		d.LastError = err.Error()
+		if _, ok := err.(permanentError); ok {
+			errs = append(errs, fmt.Errorf("notification to %s was rejected, giving up: %v", d.Url, err))
+		} else if d.Attempts >= maxAttempts {
+			errs = append(errs, fmt.Errorf("notification to %s failed %d times, giving up: %v", d.Url, d.Attempts, err))
+		} else {
+			d.NextAttempt = now.Add(retryDelay(d.Attempts))
+			errs = append(errs, fmt.Errorf("notification to %s failed, will retry after %s: %v", d.Url, d.NextAttempt.Format("2006-01-02 15:04:05"), err))
+			remaining = append(remaining, d)
+		}
+	}
+	q.Deliveries = remaining
+	return sent, errs
+}
+
+// Send posts the given payload to the webhook.
+func (q *Queue) Send(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wadman")
+
+	client := q.Http
+	if client == nil {
+		client = defaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1<<16))
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return fmt.Errorf("unexpected response: %s", res.Status)
+	default:
+		return permanentError{fmt.Errorf("unexpected response: %s", res.Status)}
+	}
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := initialRetryDelay << uint(attempts-1)
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
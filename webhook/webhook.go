@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Format determines the shape of the payload sent to a webhook.
+type Format string
+
+const (
+	// FormatJson sends the full summary as a JSON object, along with the rendered message in its "text" field.
+	FormatJson Format = "json"
+	// FormatDiscord sends the rendered message as a Discord webhook message.
+	FormatDiscord Format = "discord"
+	// FormatSlack sends the rendered message as a Slack incoming webhook message.
+	FormatSlack Format = "slack"
+)
+
+// discordMessageLimit is the maximum number of characters Discord accepts in a message.
+const discordMessageLimit = 2000
+
+// DefaultTemplate is used to render the message for targets that don't specify their own template.
+const DefaultTemplate = `{{if .Updated}}Updated {{len .Updated}} addon(s) on {{.Host}}:
+{{range .Updated}}- {{.Name}}: {{.OldVersion}} -> {{.NewVersion}}
+{{end}}{{end}}{{if .Failed}}Failed to update {{len .Failed}} addon(s) on {{.Host}}:
+{{range .Failed}}- {{.Name}}: {{.Error}}
+{{end}}{{end}}`
+
+// Target is a webhook that is notified after addons are updated.
+type Target struct {
+	Url    string `json:"url"`
+	Format Format `json:"format"`
+	// Template is a text/template used to render the message, with a Summary as its data. DefaultTemplate is used if
+	// it is empty.
+	Template string `json:"template,omitempty"`
+}
+
+// Summary describes the outcome of updating addons.
+type Summary struct {
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	Updated []Update  `json:"updated"`
+	Failed  []Failure `json:"failed"`
+}
+
+// Update is an addon that was updated successfully.
+type Update struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+}
+
+// Failure is an addon that could not be updated.
+type Failure struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// Empty determines whether the summary has nothing worth reporting.
+func (s *Summary) Empty() bool {
+	return len(s.Updated) == 0 && len(s.Failed) == 0
+}
+
+// ParseFormat validates the name of a payload format.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatJson, FormatDiscord, FormatSlack:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown webhook format '%s' (expected json, discord or slack)", name)
+	}
+}
+
+// ParseTemplate checks that the given message template is valid. An empty template is replaced with DefaultTemplate.
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	return template.New("webhook").Option("missingkey=error").Parse(text)
+}
+
+// Render produces the message describing the summary, using the target's template.
+func (t Target) Render(summary *Summary) (string, error) {
+	tmpl, err := ParseTemplate(t.Template)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, summary); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Payload builds the body of the request sent to the target for the given summary.
+func (t Target) Payload(summary *Summary) ([]byte, error) {
+	text, err := t.Render(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Format {
+	case FormatDiscord:
+		if runes := []rune(text); len(runes) > discordMessageLimit {
+			text = string(runes[:discordMessageLimit-1]) + "…"
+		}
+		return json.Marshal(&struct {
+			Content  string `json:"content"`
+			Username string `json:"username"`
+		}{text, "wadman"})
+
+	case FormatSlack:
+		return json.Marshal(&struct {
+			Text string `json:"text"`
+		}{text})
+
+	case FormatJson, "":
+		return json.Marshal(&struct {
+			Text string `json:"text"`
+			*Summary
+		}{text, summary})
+
+	default:
+		return nil, fmt.Errorf("unknown webhook format '%s'", t.Format)
+	}
+}
@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testSummary = &Summary{
+	Time: time.Date(2022, time.November, 12, 10, 30, 0, 0, time.UTC),
+	Host: "gaming-pc",
+	Updated: []Update{
+		{Id: "curse:3358", Name: "Deadly Boss Mods", OldVersion: "10.0.1", NewVersion: "10.0.2"},
+	},
+	Failed: []Failure{
+		{Id: "wowi:15749", Name: "Threat Plates", Error: "download failed"},
+	},
+}
+
+const testMessage = "Updated 1 addon(s) on gaming-pc:\n" +
+	"- Deadly Boss Mods: 10.0.1 -> 10.0.2\n" +
+	"Failed to update 1 addon(s) on gaming-pc:\n" +
+	"- Threat Plates: download failed"
+
+func TestTarget_Payload(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   map[string]interface{}
+	}{
+		{
+			format: FormatDiscord,
+			want:   map[string]interface{}{"content": testMessage, "username": "wadman"},
+		},
+		{
+			format: FormatSlack,
+			want:   map[string]interface{}{"text": testMessage},
+		},
+		{
+			format: FormatJson,
+			want: map[string]interface{}{
+				"text": testMessage,
+				"time": "2022-11-12T10:30:00Z",
+				"host": "gaming-pc",
+				"updated": []interface{}{map[string]interface{}{
+					"id": "curse:3358", "name": "Deadly Boss Mods", "old_version": "10.0.1", "new_version": "10.0.2",
+				}},
+				"failed": []interface{}{map[string]interface{}{
+					"id": "wowi:15749", "name": "Threat Plates", "error": "download failed",
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			b, err := Target{Url: "https://example.com", Format: tt.format}.Payload(testSummary)
+			if err != nil {
+				t.Fatalf("Payload() failed: %v", err)
+			}
+
+			got := make(map[string]interface{})
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("Payload() returned invalid JSON: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Payload() = %s", b)
+			}
+		})
+	}
+}
+
+func TestTarget_PayloadTemplate(t *testing.T) {
+	target := Target{Format: FormatSlack, Template: "{{.Host}}: {{len .Updated}} updated, {{len .Failed}} failed"}
+	b, err := target.Payload(testSummary)
+	if err != nil {
+		t.Fatalf("Payload() failed: %v", err)
+	}
+
+	if string(b) != `{"text":"gaming-pc: 1 updated, 1 failed"}` {
+		t.Errorf("Payload() = %s", b)
+	}
+
+	if _, err := (Target{Format: FormatSlack, Template: "{{.Missing}}"}).Payload(testSummary); err == nil {
+		t.Errorf("Payload() succeeded with a template using a missing field")
+	}
+
+	if _, err := (Target{Format: "carrier-pigeon"}).Payload(testSummary); err == nil {
+		t.Errorf("Payload() succeeded with an unknown format")
+	}
+}
+
+func TestTarget_PayloadDiscordLimit(t *testing.T) {
+	target := Target{Format: FormatDiscord, Template: strings.Repeat("é", discordMessageLimit+10)}
+	b, err := target.Payload(testSummary)
+	if err != nil {
+		t.Fatalf("Payload() failed: %v", err)
+	}
+
+	message := &struct {
+		Content string `json:"content"`
+	}{}
+	if err := json.Unmarshal(b, message); err != nil {
+		t.Fatal(err)
+	}
+
+	if runes := []rune(message.Content); len(runes) != discordMessageLimit || runes[len(runes)-1] != '…' {
+		t.Errorf("message was %d characters, want it truncated to %d", len(runes), discordMessageLimit)
+	}
+}
+
+// webhookServer records the bodies posted to it, and responds with each of the given statuses in turn. Once they
+// run out it responds with 204 No Content.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request with content type %s", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bodies = append(s.bodies, string(b))
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func newQueue(t *testing.T, server *webhookServer) *Queue {
+	queue, err := LoadQueue(filepath.Join(t.TempDir(), "webhooks.json"))
+	if err != nil {
+		t.Fatalf("LoadQueue() failed: %v", err)
+	}
+
+	queue.Http = server.Client()
+	if err := queue.Add(Target{Url: server.URL, Format: FormatSlack}, testSummary); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	return queue
+}
+
+func TestQueue_FlushSuccess(t *testing.T) {
+	server := newWebhookServer(t)
+	queue := newQueue(t, server)
+
+	sent, errs := queue.Flush(context.Background())
+	if sent != 1 || len(errs) != 0 {
+		t.Fatalf("Flush() = %d, %v, want 1 sent with no errors", sent, errs)
+	}
+
+	if len(queue.Deliveries) != 0 {
+		t.Errorf("delivered notification was left in the queue")
+	}
+
+	want, _ := Target{Format: FormatSlack}.Payload(testSummary)
+	if server.bodies[0] != string(want) {
+		t.Errorf("webhook received %s, want %s", server.bodies[0], want)
+	}
+}
+
+func TestQueue_FlushRetries(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := newWebhookServer(t, status, status)
+			queue := newQueue(t, server)
+
+			for attempt := 1; attempt <= 2; attempt++ {
+				before := time.Now()
+				sent, errs := queue.Flush(context.Background())
+				if sent != 0 || len(errs) != 1 {
+					t.Fatalf("Flush() = %d, %v, want an error", sent, errs)
+				}
+
+				if len(queue.Deliveries) != 1 {
+					t.Fatalf("failed notification wasn't kept for a retry")
+				}
+
+				d := queue.Deliveries[0]
+				delay := initialRetryDelay << uint(attempt-1)
+				if d.Attempts != attempt || d.NextAttempt.Before(before.Add(delay)) || d.NextAttempt.After(time.Now().Add(delay)) {
+					t.Errorf("after attempt %d: attempts = %d, next attempt in %s, want %s", attempt, d.Attempts, d.NextAttempt.Sub(before), delay)
+				}
+				if !strings.Contains(d.LastError, http.StatusText(status)) {
+					t.Errorf("last error = %s", d.LastError)
+				}
+
+				// Deliveries aren't retried until they are due
+				if sent, errs := queue.Flush(context.Background()); sent != 0 || len(errs) != 0 || server.requests() != attempt {
+					t.Fatalf("Flush() retried a delivery early: %d, %v", sent, errs)
+				}
+
+				d.NextAttempt = time.Now().Add(-time.Second)
+			}
+
+			if sent, errs := queue.Flush(context.Background()); sent != 1 || len(errs) != 0 || len(queue.Deliveries) != 0 {
+				t.Errorf("Flush() = %d, %v, want the retry to succeed", sent, errs)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:   time.Minute,
+		2:   2 * time.Minute,
+		5:   16 * time.Minute,
+		9:   4*time.Hour + 16*time.Minute,
+		10:  maxRetryDelay,
+		100: maxRetryDelay,
+	}
+	for attempts, want := range tests {
+		if got := retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestQueue_FlushGivesUp(t *testing.T) {
+	server := newWebhookServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	queue := newQueue(t, server)
+	queue.Deliveries[0].Attempts = maxAttempts - 1
+
+	sent, errs := queue.Flush(context.Background())
+	if sent != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "giving up") {
+		t.Errorf("Flush() = %d, %v, want it to give up", sent, errs)
+	}
+
+	if len(queue.Deliveries) != 0 {
+		t.Errorf("notification was kept after running out of attempts")
+	}
+}
+
+func TestQueue_FlushDropsRejected(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusGone} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := newWebhookServer(t, status)
+			queue := newQueue(t, server)
+
+			sent, errs := queue.Flush(context.Background())
+			if sent != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "rejected") {
+				t.Errorf("Flush() = %d, %v, want it to be rejected", sent, errs)
+			}
+
+			if len(queue.Deliveries) != 0 {
+				t.Errorf("rejected notification was kept for a retry")
+			}
+
+			if server.requests() != 1 {
+				t.Errorf("webhook received %d requests, want 1", server.requests())
+			}
+		})
+	}
+}
+
+func TestQueue_SaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache", "webhooks.json")
+	queue, err := LoadQueue(path)
+	if err != nil || len(queue.Deliveries) != 0 {
+		t.Fatalf("LoadQueue() = %v, %v, want an empty queue", queue, err)
+	}
+
+	if err := queue.Add(Target{Url: "https://example.com/a", Format: FormatJson}, testSummary); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Add(Target{Url: "https://example.com/b", Format: FormatDiscord}, testSummary); err != nil {
+		t.Fatal(err)
+	}
+	queue.Deliveries[1].Attempts = 3
+	queue.Deliveries[1].NextAttempt = time.Date(2022, time.November, 12, 11, 0, 0, 0, time.UTC)
+	queue.Deliveries[1].LastError = "unexpected response: 502 Bad Gateway"
+
+	if err := queue.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("queue file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+		}
+	}
+
+	if files, _ := ioutil.ReadDir(filepath.Dir(path)); len(files) != 1 {
+		t.Errorf("found %d files after saving, want just the queue", len(files))
+	}
+
+	loaded, err := LoadQueue(path)
+	if err != nil {
+		t.Fatalf("LoadQueue() failed: %v", err)
+	}
+
+	if len(loaded.Deliveries) != len(queue.Deliveries) {
+		t.Fatalf("LoadQueue() returned %d deliveries, want %d", len(loaded.Deliveries), len(queue.Deliveries))
+	}
+
+	for i := range queue.Deliveries {
+		// The saved file is indented, which also applies to the bodies, so compare them without whitespace
+		got, want := *loaded.Deliveries[i], *queue.Deliveries[i]
+		got.Body, want.Body = compact(t, got.Body), compact(t, want.Body)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("LoadQueue() delivery %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	loaded.Deliveries = nil
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("empty queue wasn't removed: %v", err)
+	}
+}
+
+func compact(t *testing.T, b json.RawMessage) json.RawMessage {
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, b); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadQueue_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	if err := ioutil.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadQueue(path); err == nil {
+		t.Errorf("LoadQueue() succeeded with an invalid file")
+	}
+}
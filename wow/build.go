@@ -0,0 +1,106 @@
+package wow
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// buildInfoFile is the name of the file in the base WoW directory that describes the installed game clients.
+const buildInfoFile = ".build.info"
+
+// products maps the names of the directories game clients are installed in to the product code used for them in the
+// build info file.
+var products = map[string]string{
+	"_retail_":       "wow",
+	"_ptr_":          "wowt",
+	"_beta_":         "wow_beta",
+	"_classic_":      "wow_classic",
+	"_classic_ptr_":  "wow_classic_ptr",
+	"_classic_beta_": "wow_classic_beta",
+	"_classic_era_":  "wow_classic_era",
+}
+
+// ClientVersion reads the version of the game client (e.g. "10.0.2.47213") from the build info file in the directory
+// above the install.
+func (w *Install) ClientVersion() (string, error) {
+	f, err := os.Open(filepath.Join(filepath.Dir(w.path), buildInfoFile))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return "", fmt.Errorf("build info file is empty")
+	}
+
+	// Column headers are in the form "Name!TYPE:size"
+	columns := make(map[string]int)
+	for i, header := range strings.Split(scanner.Text(), "|") {
+		columns[strings.SplitN(header, "!", 2)[0]] = i
+	}
+
+	versionColumn, ok := columns["Version"]
+	if !ok {
+		return "", fmt.Errorf("build info file doesn't contain versions")
+	}
+
+	productColumn, hasProducts := columns["Product"]
+	product := products[strings.ToLower(filepath.Base(w.path))]
+	var versions []string
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) <= versionColumn || fields[versionColumn] == "" {
+			continue
+		}
+
+		if hasProducts && product != "" && len(fields) > productColumn && fields[productColumn] == product {
+			return fields[versionColumn], nil
+		}
+		versions = append(versions, fields[versionColumn])
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	// Older files don't list products, and only contain the one client
+	if len(versions) == 1 {
+		return versions[0], nil
+	}
+	return "", fmt.Errorf("unable to find client version for %s", filepath.Base(w.path))
+}
+
+// ClientInterface returns the interface version of the game client, in the form used in TOC files (e.g. 100002).
+func (w *Install) ClientInterface() (int, error) {
+	version, err := w.ClientVersion()
+	if err != nil {
+		return 0, err
+	}
+	return InterfaceVersion(version)
+}
+
+// InterfaceVersion converts a client version such as "10.0.2.47213" into an interface version such as 100002.
+// Interface versions are also accepted, and returned as-is.
+func InterfaceVersion(version string) (int, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) == 1 {
+		return strconv.Atoi(version)
+	}
+	if len(parts) < 3 {
+		return 0, fmt.Errorf("invalid client version: %s", version)
+	}
+
+	res := 0
+	for _, part := range parts[:3] {
+		n, err := strconv.Atoi(part)
+		if err != nil || n > 99 {
+			return 0, fmt.Errorf("invalid client version: %s", version)
+		}
+		res = res*100 + n
+	}
+	return res, nil
+}
@@ -0,0 +1,222 @@
+package wow
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	addonsFile         = "AddOns.txt"
+	savedVariablesDir  = "SavedVariables"
+	addonStateEnabled  = "enabled"
+	addonStateDisabled = "disabled"
+)
+
+// Account is a Battle.net game account that has been used with the WoW install.
+type Account struct {
+	Name   string
+	Realms []*Realm
+}
+
+// Realm is a realm that has characters belonging to an account.
+type Realm struct {
+	Name       string
+	Characters []*Character
+}
+
+// Character is a single character, which has its own set of enabled and disabled addons.
+type Character struct {
+	Account string
+	Realm   string
+	Name    string
+	path    string
+}
+
+// String returns the realm and name of the character in the form "realm/name".
+func (c *Character) String() string {
+	return fmt.Sprintf("%s/%s", c.Realm, c.Name)
+}
+
+// Matches determines whether the character is identified by the given "realm/name" string. Comparisons are
+// case-insensitive.
+func (c *Character) Matches(id string) bool {
+	return strings.EqualFold(c.String(), id)
+}
+
+// Accounts returns all of the accounts, realms and characters that have settings stored in the WoW install.
+func (w *Install) Accounts() ([]*Account, error) {
+	accountDirs, err := subDirs(filepath.Join(w.path, "WTF", "Account"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var accounts []*Account
+	for _, accountName := range accountDirs {
+		account := &Account{Name: accountName}
+		realmDirs, err := subDirs(filepath.Join(w.path, "WTF", "Account", accountName))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, realmName := range realmDirs {
+			realm := &Realm{Name: realmName}
+			characterDirs, err := subDirs(filepath.Join(w.path, "WTF", "Account", accountName, realmName))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, characterName := range characterDirs {
+				realm.Characters = append(realm.Characters, &Character{
+					Account: accountName,
+					Realm:   realmName,
+					Name:    characterName,
+					path:    filepath.Join(w.path, "WTF", "Account", accountName, realmName, characterName),
+				})
+			}
+
+			if len(realm.Characters) > 0 {
+				account.Realms = append(account.Realms, realm)
+			}
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// SavedVariablesPath returns the path of the account-wide SavedVariables file for the given addon.
+func (w *Install) SavedVariablesPath(account string, addon string) string {
+	return filepath.Join(w.path, "WTF", "Account", account, savedVariablesDir, fmt.Sprintf("%s.lua", addon))
+}
+
+// Characters returns a flattened list of all characters across all accounts and realms.
+func (w *Install) Characters() ([]*Character, error) {
+	accounts, err := w.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var characters []*Character
+	for _, a := range accounts {
+		for _, r := range a.Realms {
+			characters = append(characters, r.Characters...)
+		}
+	}
+	return characters, nil
+}
+
+// DisabledAddons returns a map of addons that have been disabled for the character.
+func (c *Character) DisabledAddons() (map[string]bool, error) {
+	disabled := make(map[string]bool)
+	lines, _, err := c.readAddonsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		if name, state, ok := parseAddonLine(line); ok && state == addonStateDisabled {
+			disabled[name] = true
+		}
+	}
+
+	return disabled, nil
+}
+
+// SetAddonsEnabled rewrites the character's AddOns.txt file to enable or disable the given addons. Entries for other
+// addons are left untouched.
+func (c *Character) SetAddonsEnabled(names []string, enabled bool) error {
+	lines, newline, err := c.readAddonsFile()
+	if err != nil {
+		return err
+	}
+
+	state := addonStateDisabled
+	if enabled {
+		state = addonStateEnabled
+	}
+
+	pending := make(map[string]bool)
+	for _, n := range names {
+		pending[n] = true
+	}
+
+	for i, line := range lines {
+		if name, _, ok := parseAddonLine(line); ok && pending[name] {
+			lines[i] = fmt.Sprintf("%s: %s", name, state)
+			delete(pending, name)
+		}
+	}
+
+	var missing []string
+	for n := range pending {
+		missing = append(missing, n)
+	}
+	sort.Strings(missing)
+	for _, n := range missing {
+		lines = append(lines, fmt.Sprintf("%s: %s", n, state))
+	}
+
+	content := strings.Join(lines, newline)
+	if len(lines) > 0 {
+		content += newline
+	}
+	return ioutil.WriteFile(filepath.Join(c.path, addonsFile), []byte(content), os.FileMode(0644))
+}
+
+// readAddonsFile returns the non-blank lines in the character's AddOns.txt file, and the line ending used within it.
+func (c *Character) readAddonsFile() (lines []string, newline string, err error) {
+	newline = "\n"
+	b, err := ioutil.ReadFile(filepath.Join(c.path, addonsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, newline, nil
+		}
+		return nil, newline, err
+	}
+
+	if strings.Contains(string(b), "\r\n") {
+		newline = "\r\n"
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(b)))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines, newline, scanner.Err()
+}
+
+// parseAddonLine splits a line from AddOns.txt into its addon name and state.
+func parseAddonLine(line string) (name string, state string, ok bool) {
+	i := strings.LastIndex(line, ":")
+	if i == -1 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
+
+// subDirs returns the names of all directories within the given path, excluding SavedVariables directories.
+func subDirs(path string) ([]string, error) {
+	fs, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for i := range fs {
+		if fs[i].IsDir() && fs[i].Name() != savedVariablesDir {
+			dirs = append(dirs, fs[i].Name())
+		}
+	}
+	return dirs, nil
+}
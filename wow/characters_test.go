@@ -0,0 +1,207 @@
+package wow
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// copyFixture copies the install in testdata to a temporary directory, so that tests can modify it.
+func copyFixture(t *testing.T) *Install {
+	t.Helper()
+
+	source := filepath.Join("testdata", "_retail_")
+	target := filepath.Join(t.TempDir(), "_retail_")
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(target, rel), os.FileMode(0755))
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(target, rel), b, os.FileMode(0644))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewWowInstall(target)
+}
+
+func findCharacter(t *testing.T, w *Install, id string) *Character {
+	t.Helper()
+
+	characters, err := w.Characters()
+	if err != nil {
+		t.Fatalf("Characters() failed: %v", err)
+	}
+
+	for _, c := range characters {
+		if c.Matches(id) {
+			return c
+		}
+	}
+	t.Fatalf("character %s not found", id)
+	return nil
+}
+
+func TestAccounts(t *testing.T) {
+	accounts, err := NewWowInstall(filepath.Join("testdata", "_retail_")).Accounts()
+	if err != nil {
+		t.Fatalf("Accounts() failed: %v", err)
+	}
+
+	var got []string
+	for _, a := range accounts {
+		got = append(got, a.Name)
+		for _, r := range a.Realms {
+			for _, c := range r.Characters {
+				if c.Account != a.Name || c.Realm != r.Name {
+					t.Errorf("character %s has account %s, want %s", c, c.Account, a.Name)
+				}
+				got = append(got, c.Account+"/"+c.String())
+			}
+		}
+	}
+
+	// Realms without characters and SavedVariables directories are skipped, but accounts are always listed
+	want := []string{
+		"ACCOUNT1",
+		"ACCOUNT1/Silvermoon/Alice",
+		"ACCOUNT1/Silvermoon/Bob",
+		"ACCOUNT2",
+		"ACCOUNT2/Kazzak/Carol",
+		"ACCOUNT3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Accounts() = %v, want %v", got, want)
+	}
+}
+
+func TestAccounts_NoSettings(t *testing.T) {
+	accounts, err := NewWowInstall(t.TempDir()).Accounts()
+	if err != nil || accounts != nil {
+		t.Errorf("Accounts() = %v, %v, want no accounts", accounts, err)
+	}
+}
+
+func TestCharacter_Matches(t *testing.T) {
+	c := &Character{Realm: "Silvermoon", Name: "Alice"}
+	tests := map[string]bool{
+		"Silvermoon/Alice": true,
+		"silvermoon/ALICE": true,
+		"Silvermoon":       false,
+		"Draenor/Alice":    false,
+	}
+	for id, want := range tests {
+		if got := c.Matches(id); got != want {
+			t.Errorf("Matches(%s) = %t, want %t", id, got, want)
+		}
+	}
+}
+
+func TestDisabledAddons(t *testing.T) {
+	w := NewWowInstall(filepath.Join("testdata", "_retail_"))
+	tests := map[string]map[string]bool{
+		"Silvermoon/Alice": {"Bar": true, "Baz:Extra": true},
+		"Silvermoon/Bob":   {},
+		"Kazzak/Carol":     {"Foo": true},
+	}
+	for id, want := range tests {
+		got, err := findCharacter(t, w, id).DisabledAddons()
+		if err != nil {
+			t.Errorf("DisabledAddons(%s) failed: %v", id, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DisabledAddons(%s) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestSetAddonsEnabled(t *testing.T) {
+	tests := []struct {
+		character string
+		names     []string
+		enabled   bool
+		want      string
+	}{
+		{
+			character: "Silvermoon/Alice",
+			names:     []string{"Bar", "Qux", "Foo", "Baz:Extra"},
+			enabled:   true,
+			want:      "Foo: enabled\nBar: enabled\nBaz:Extra: enabled\nQux: enabled\n",
+		},
+		{
+			character: "Silvermoon/Alice",
+			names:     []string{"Foo"},
+			enabled:   false,
+			want:      "Foo: disabled\nBar: disabled\nBaz:Extra: disabled\n",
+		},
+		{
+			character: "Silvermoon/Bob",
+			names:     []string{"Zed", "Foo"},
+			enabled:   false,
+			want:      "Foo: disabled\nZed: disabled\n",
+		},
+		{
+			character: "Silvermoon/Bob",
+			enabled:   true,
+			want:      "",
+		},
+	}
+	for _, tt := range tests {
+		c := findCharacter(t, copyFixture(t), tt.character)
+		if err := c.SetAddonsEnabled(tt.names, tt.enabled); err != nil {
+			t.Errorf("SetAddonsEnabled(%s, %v) failed: %v", tt.character, tt.names, err)
+			continue
+		}
+
+		if got, err := ioutil.ReadFile(filepath.Join(c.path, addonsFile)); err != nil || string(got) != tt.want {
+			t.Errorf("SetAddonsEnabled(%s, %v) wrote %q, %v, want %q", tt.character, tt.names, got, err, tt.want)
+		}
+	}
+}
+
+func TestSetAddonsEnabled_WindowsLineEndings(t *testing.T) {
+	c := findCharacter(t, copyFixture(t), "Kazzak/Carol")
+	path := filepath.Join(c.path, addonsFile)
+	if err := ioutil.WriteFile(path, []byte("Foo: disabled\r\nBar: enabled\r\n"), os.FileMode(0644)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetAddonsEnabled([]string{"Foo", "Baz"}, true); err != nil {
+		t.Fatalf("SetAddonsEnabled() failed: %v", err)
+	}
+
+	want := "Foo: enabled\r\nBar: enabled\r\nBaz: enabled\r\n"
+	if got, err := ioutil.ReadFile(path); err != nil || string(got) != want {
+		t.Errorf("SetAddonsEnabled() wrote %q, %v, want %q", got, err, want)
+	}
+
+	if disabled, err := c.DisabledAddons(); err != nil || len(disabled) != 0 {
+		t.Errorf("DisabledAddons() = %v, %v, want none", disabled, err)
+	}
+}
+
+func TestSavedVariablesPath(t *testing.T) {
+	w := NewWowInstall(filepath.Join("testdata", "_retail_"))
+	path := w.SavedVariablesPath("ACCOUNT1", "Foo")
+	if b, err := ioutil.ReadFile(path); err != nil || !strings.HasPrefix(string(b), "FooDB = {") {
+		t.Errorf("SavedVariablesPath() = %s, which contains %q, %v", path, b, err)
+	}
+}
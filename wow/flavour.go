@@ -0,0 +1,64 @@
+package wow
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Flavour is a variant of the game, such as retail or classic.
+type Flavour string
+
+const (
+	FlavourUnknown        Flavour = ""
+	FlavourRetail         Flavour = "retail"
+	FlavourClassic        Flavour = "classic"
+	FlavourBurningCrusade Flavour = "bcc"
+	FlavourWrath          Flavour = "wrath"
+)
+
+// ParseFlavour converts the given string to a Flavour, returning an error if it is not recognised.
+func ParseFlavour(s string) (Flavour, error) {
+	switch f := Flavour(strings.ToLower(s)); f {
+	case FlavourRetail, FlavourClassic, FlavourBurningCrusade, FlavourWrath:
+		return f, nil
+	default:
+		return FlavourUnknown, fmt.Errorf("unknown flavour '%s' (expected retail, classic, bcc or wrath)", s)
+	}
+}
+
+// FlavourForVersion determines which flavour of the game a client version (such as "9.0.2") belongs to.
+func FlavourForVersion(version string) Flavour {
+	switch {
+	case version == "":
+		return FlavourUnknown
+	case strings.HasPrefix(version, "1."):
+		return FlavourClassic
+	case strings.HasPrefix(version, "2."):
+		return FlavourBurningCrusade
+	case strings.HasPrefix(version, "3."):
+		return FlavourWrath
+	default:
+		return FlavourRetail
+	}
+}
+
+// flavourDirs maps the names of the directories the game client is installed in to the flavour they contain.
+var flavourDirs = map[string]Flavour{
+	"_retail_":       FlavourRetail,
+	"_ptr_":          FlavourRetail,
+	"_beta_":         FlavourRetail,
+	"_classic_era_":  FlavourClassic,
+	"_classic_":      FlavourWrath,
+	"_classic_ptr_":  FlavourWrath,
+	"_classic_beta_": FlavourWrath,
+}
+
+// Flavour guesses which flavour of the game the install is for based on the name of its directory, assuming retail
+// if it isn't recognised.
+func (w *Install) Flavour() Flavour {
+	if flavour, ok := flavourDirs[strings.ToLower(filepath.Base(w.path))]; ok {
+		return flavour
+	}
+	return FlavourRetail
+}
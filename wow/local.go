@@ -0,0 +1,249 @@
+package wow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ignoredDirs contains the names of directories that are never copied from local sources, such as version control
+// meta-data.
+var ignoredDirs = map[string]bool{
+	".git": true,
+	".hg":  true,
+	".svn": true,
+}
+
+// FindAddonDirs returns the addon directories contained in the given path, keyed on the name they should be
+// installed as. If the path itself contains a TOC file it is treated as a single addon named after the TOC file,
+// otherwise each subdirectory that contains a TOC file is treated as a separate addon.
+func FindAddonDirs(path string) (map[string]string, error) {
+	if name, ok := tocName(path); ok {
+		return map[string]string{name: path}, nil
+	}
+
+	fs, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string)
+	for _, f := range fs {
+		if !f.IsDir() || ignoredDirs[f.Name()] {
+			continue
+		}
+
+		if _, ok := tocName(filepath.Join(path, f.Name())); ok {
+			res[f.Name()] = filepath.Join(path, f.Name())
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no addons found in %s", path)
+	}
+	return res, nil
+}
+
+// tocName returns the name of the addon whose TOC file is in the given directory, if there is one.
+func tocName(path string) (string, bool) {
+	matches, _ := filepath.Glob(filepath.Join(path, "*.toc"))
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	// Flavour-specific TOC files have suffixes such as "_Mainline", so the shortest name is the addon's own
+	var best string
+	for _, m := range matches {
+		name := strings.TrimSuffix(filepath.Base(m), filepath.Ext(m))
+		if name == filepath.Base(path) {
+			return name, true
+		}
+		if best == "" || len(name) < len(best) || (len(name) == len(best) && name < best) {
+			best = name
+		}
+	}
+	return best, true
+}
+
+// Fingerprint computes a hex-encoded SHA-256 checksum of the names, sizes and modification times of every file in the
+// given directories. It changes whenever any file is added, removed or modified, without reading file contents. The
+// most recent modification time of any file is also returned.
+func Fingerprint(dirs map[string]string) (string, time.Time, error) {
+	var names []string
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var modified time.Time
+	hash := sha256.New()
+	for _, name := range names {
+		err := walk(dirs[name], func(rel string, info os.FileInfo) error {
+			if info.ModTime().After(modified) {
+				modified = info.ModTime()
+			}
+			_, err := fmt.Fprintf(hash, "%s/%s\x00%d\x00%d\n", name, filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano())
+			return err
+		})
+		if err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), modified, nil
+}
+
+// ReplaceAddonsFromDirs deploys the given directories (keyed on the name they should be installed as) to the WoW
+// addons directory in place of the given existing addons, returning a slice of folder names that were created. If
+// link is true, symbolic links to the directories are created instead of copying them.
+//
+// As with ReplaceAddons, the directories are staged first and the existing addons are only replaced once staging
+// has finished successfully.
+func (w *Install) ReplaceAddonsFromDirs(ctx context.Context, dirs map[string]string, link bool, old []string) ([]string, error) {
+	if err := os.MkdirAll(w.addonsPath, os.FileMode(0755)); err != nil {
+		return nil, err
+	}
+
+	staging, err := ioutil.TempDir(filepath.Dir(w.addonsPath), ".wadman-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	source := filepath.Join(staging, "new")
+	if err := os.MkdirAll(source, os.FileMode(0755)); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name, path := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if link {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			err = os.Symlink(abs, filepath.Join(source, name))
+		} else {
+			err = copyDir(ctx, path, filepath.Join(source, name))
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	if err := w.swapAddons(source, filepath.Join(staging, "old"), names, old); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// AddonPath returns the path to the directory of the named addon. The directory may not exist.
+func (w *Install) AddonPath(addon string) string {
+	return filepath.Join(w.addonsPath, addon)
+}
+
+// LockPath returns the path of the file used to stop several copies of wadman changing the install's addons at the
+// same time. It sits next to the addons directory, alongside the directories used to stage new addons.
+func (w *Install) LockPath() string {
+	return filepath.Join(filepath.Dir(w.addonsPath), ".wadman.lock")
+}
+
+// WriteAddon creates or replaces the named addon with one containing the given files, keyed on their path relative to
+// the addon's directory. It is used for addons that are generated rather than downloaded.
+func (w *Install) WriteAddon(name string, files map[string][]byte) error {
+	if err := os.MkdirAll(w.addonsPath, os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	staging, err := ioutil.TempDir(filepath.Dir(w.addonsPath), ".wadman-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	source := filepath.Join(staging, "new")
+	for rel, content := range files {
+		path := filepath.Join(source, name, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, content, os.FileMode(0644)); err != nil {
+			return err
+		}
+	}
+
+	return w.swapAddons(source, filepath.Join(staging, "old"), []string{name}, []string{name})
+}
+
+// copyDir recursively copies the source directory to the target, skipping version control directories. The context
+// is checked before each file is copied.
+func copyDir(ctx context.Context, source, target string) error {
+	if err := os.MkdirAll(target, os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	return walk(source, func(rel string, info os.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		path := filepath.Join(target, rel)
+		if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+			return err
+		}
+
+		in, err := os.Open(filepath.Join(source, rel))
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		_, err = io.Copy(out, in)
+		return err
+	})
+}
+
+// walk calls fn for every regular file beneath the given directory, in lexical order, with its path relative to
+// the directory. Version control directories are skipped.
+func walk(dir string, fn func(rel string, info os.FileInfo) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != dir && ignoredDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(rel, info)
+	})
+}
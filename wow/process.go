@@ -0,0 +1,41 @@
+package wow
+
+import "strings"
+
+// clientExecutables contains the lower-cased names of the game client executables for each flavour and test realm.
+var clientExecutables = map[string]bool{
+	"wow.exe":              true,
+	"wowt.exe":             true,
+	"wowb.exe":             true,
+	"wowclassic.exe":       true,
+	"wowclassict.exe":      true,
+	"wowclassicb.exe":      true,
+	"world of warcraft":    true,
+	"wow-64.exe":           true,
+	"world of warcraft-64": true,
+}
+
+// ClientRunning determines whether a World of Warcraft client appears to be running, by looking for its process.
+// Clients running under Wine are detected on Linux.
+func ClientRunning() (bool, error) {
+	names, err := processNames()
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if isClientExecutable(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isClientExecutable determines whether the given path (which may be a Windows path, if running under Wine) refers
+// to a game client.
+func isClientExecutable(path string) bool {
+	if i := strings.LastIndexAny(path, `/\`); i > -1 {
+		path = path[i+1:]
+	}
+	return clientExecutables[strings.ToLower(strings.TrimSpace(path))]
+}
@@ -0,0 +1,29 @@
+package wow
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// processNames returns the command name and executable path of every running process. Under Wine, the executable
+// path is the Windows path of the program being run.
+func processNames() ([]string, error) {
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, dir := range dirs {
+		// Processes may exit while we're looking at them, so errors are ignored
+		if comm, err := ioutil.ReadFile(filepath.Join(dir, "comm")); err == nil {
+			names = append(names, strings.TrimSpace(string(comm)))
+		}
+		if cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+			names = append(names, string(bytes.SplitN(cmdline, []byte{0}, 2)[0]))
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package wow
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// processNames returns the executable path of every running process.
+func processNames() ([]string, error) {
+	out, err := exec.Command("ps", "-axo", "comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(out), "\n"), nil
+}
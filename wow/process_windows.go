@@ -0,0 +1,28 @@
+package wow
+
+import (
+	"encoding/csv"
+	"os/exec"
+	"strings"
+)
+
+// processNames returns the image name of every running process.
+func processNames() ([]string, error) {
+	out, err := exec.Command("tasklist", "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(out))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, r := range records {
+		if len(r) > 0 {
+			names = append(names, r[0])
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,601 @@
+package savedvars
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// positionComment matches the comments WoW adds after positional table entries, such as `-- [1]`.
+var positionComment = regexp.MustCompile(`^--\s*\[\d+]$`)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenName
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	typ   tokenType
+	text  string
+	value Value
+	line  int
+	// comments are the comments that appeared between the previous token and this one.
+	comments []comment
+}
+
+// comment is a Lua comment, including its leading dashes (and brackets, for block comments).
+type comment struct {
+	text string
+	// sameLine indicates that the comment started on the same line as the end of the previous token.
+	sameLine bool
+}
+
+// lexer splits the subset of Lua used in SavedVariables files into tokens.
+type lexer struct {
+	input    []byte
+	pos      int
+	line     int
+	comments []comment
+	// newline indicates whether a line break has been seen since the last token.
+	newline bool
+}
+
+func newLexer(input []byte) *lexer {
+	// Skip any UTF-8 byte order mark
+	if len(input) >= 3 && input[0] == 0xEF && input[1] == 0xBB && input[2] == 0xBF {
+		input = input[3:]
+	}
+	return &lexer{input: input, line: 1, newline: true}
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) peekByte(offset int) byte {
+	if l.pos+offset < len(l.input) {
+		return l.input[l.pos+offset]
+	}
+	return 0
+}
+
+// skipSpace skips whitespace, and records any comments so they can be attached to the next token.
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+			l.newline = true
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case c == '-' && l.peekByte(1) == '-':
+			start := l.pos
+			l.pos += 2
+			if level, ok := l.longBracket(); ok {
+				if _, err := l.readLongString(level); err != nil {
+					return err
+				}
+			} else {
+				for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+					l.pos++
+				}
+			}
+			l.comments = append(l.comments, comment{
+				text:     strings.TrimRight(string(l.input[start:l.pos]), " \t\r"),
+				sameLine: !l.newline,
+			})
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// longBracket checks for the start of a long bracket (`[[` or `[==[`) at the current position, returning its level.
+func (l *lexer) longBracket() (int, bool) {
+	if l.peekByte(0) != '[' {
+		return 0, false
+	}
+
+	level := 0
+	for l.peekByte(1+level) == '=' {
+		level++
+	}
+	if l.peekByte(1+level) != '[' {
+		return 0, false
+	}
+	return level, true
+}
+
+// readLongString reads the contents of a long bracket of the given level, starting at its opening bracket.
+func (l *lexer) readLongString(level int) (string, error) {
+	l.pos += level + 2
+	// A newline immediately after the opening bracket is skipped
+	if l.peekByte(0) == '\r' {
+		l.pos++
+	}
+	if l.peekByte(0) == '\n' {
+		l.line++
+		l.pos++
+	}
+
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(string(l.input[l.pos:]), closing)
+	if end == -1 {
+		return "", l.errorf("unfinished long string or comment")
+	}
+
+	s := string(l.input[l.pos : l.pos+end])
+	l.line += strings.Count(s, "\n")
+	l.pos += end + len(closing)
+	return s, nil
+}
+
+func (l *lexer) next() (token, error) {
+	t, err := l.nextToken()
+	if err != nil {
+		return token{}, err
+	}
+
+	t.comments = l.comments
+	l.comments = nil
+	l.newline = false
+	return t, nil
+}
+
+func (l *lexer) nextToken() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+
+	if l.pos >= len(l.input) {
+		return token{typ: tokenEOF, line: l.line}, nil
+	}
+
+	start := l.pos
+	c := l.input[l.pos]
+	switch {
+	case c == '"' || c == '\'':
+		s, err := l.readString(c)
+		if err != nil {
+			return token{}, err
+		}
+		return token{typ: tokenString, text: string(l.input[start:l.pos]), value: s, line: l.line}, nil
+	case c == '[':
+		if level, ok := l.longBracket(); ok {
+			s, err := l.readLongString(level)
+			if err != nil {
+				return token{}, err
+			}
+			return token{typ: tokenString, text: string(l.input[start:l.pos]), value: s, line: l.line}, nil
+		}
+		l.pos++
+		return token{typ: tokenSymbol, text: "[", line: l.line}, nil
+	case isDigit(c) || (c == '.' && isDigit(l.peekByte(1))):
+		return l.readNumber()
+	case isNameStart(c):
+		for l.pos < len(l.input) && isNameChar(l.input[l.pos]) {
+			l.pos++
+		}
+		return token{typ: tokenName, text: string(l.input[start:l.pos]), line: l.line}, nil
+	case strings.IndexByte("={}],;-", c) > -1:
+		l.pos++
+		return token{typ: tokenSymbol, text: string(c), line: l.line}, nil
+	default:
+		r, _ := utf8.DecodeRune(l.input[l.pos:])
+		return token{}, l.errorf("unexpected character %q", r)
+	}
+}
+
+// readString reads a quoted string, decoding any escape sequences.
+func (l *lexer) readString(quote byte) (string, error) {
+	l.pos++
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.input) {
+			return "", l.errorf("unfinished string")
+		}
+
+		c := l.input[l.pos]
+		l.pos++
+		switch c {
+		case quote:
+			return sb.String(), nil
+		case '\n':
+			return "", l.errorf("unfinished string")
+		case '\\':
+			if err := l.readEscape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+// readEscape decodes the escape sequence following a backslash.
+func (l *lexer) readEscape(sb *strings.Builder) error {
+	if l.pos >= len(l.input) {
+		return l.errorf("unfinished string")
+	}
+
+	c := l.input[l.pos]
+	l.pos++
+	switch c {
+	case 'a':
+		sb.WriteByte('\a')
+	case 'b':
+		sb.WriteByte('\b')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'r':
+		sb.WriteByte('\r')
+	case 't':
+		sb.WriteByte('\t')
+	case 'v':
+		sb.WriteByte('\v')
+	case '\\', '"', '\'':
+		sb.WriteByte(c)
+	case '\n':
+		l.line++
+		sb.WriteByte('\n')
+	case '\r':
+		if l.peekByte(0) == '\n' {
+			l.pos++
+		}
+		l.line++
+		sb.WriteByte('\n')
+	case 'z':
+		for l.pos < len(l.input) && strings.IndexByte(" \t\r\n\f\v", l.input[l.pos]) > -1 {
+			if l.input[l.pos] == '\n' {
+				l.line++
+			}
+			l.pos++
+		}
+	case 'x':
+		if l.pos+2 > len(l.input) {
+			return l.errorf("invalid hexadecimal escape")
+		}
+		v, err := strconv.ParseUint(string(l.input[l.pos:l.pos+2]), 16, 8)
+		if err != nil {
+			return l.errorf("invalid hexadecimal escape")
+		}
+		l.pos += 2
+		sb.WriteByte(byte(v))
+	case 'u':
+		end := strings.IndexByte(string(l.input[l.pos:]), '}')
+		if l.peekByte(0) != '{' || end == -1 {
+			return l.errorf("invalid unicode escape")
+		}
+		v, err := strconv.ParseUint(string(l.input[l.pos+1:l.pos+end]), 16, 32)
+		if err != nil {
+			return l.errorf("invalid unicode escape")
+		}
+		l.pos += end + 1
+		sb.WriteRune(rune(v))
+	default:
+		if !isDigit(c) {
+			return l.errorf("invalid escape sequence \\%c", c)
+		}
+		digits := 1
+		for digits < 3 && isDigit(l.peekByte(0)) {
+			l.pos++
+			digits++
+		}
+		v, err := strconv.Atoi(string(l.input[l.pos-digits : l.pos]))
+		if err != nil || v > 255 {
+			return l.errorf("invalid decimal escape")
+		}
+		sb.WriteByte(byte(v))
+	}
+	return nil
+}
+
+// readNumber reads a decimal or hexadecimal number. Integers are returned as int64 and everything else as float64.
+func (l *lexer) readNumber() (token, error) {
+	start := l.pos
+	if l.peekByte(0) == '0' && (l.peekByte(1) == 'x' || l.peekByte(1) == 'X') {
+		l.pos += 2
+		for l.pos < len(l.input) && (isHexDigit(l.input[l.pos]) || l.input[l.pos] == '.') {
+			l.pos++
+		}
+	} else {
+		for l.pos < len(l.input) {
+			c := l.input[l.pos]
+			if isDigit(c) || c == '.' {
+				l.pos++
+			} else if (c == 'e' || c == 'E') && l.pos > start {
+				l.pos++
+				if l.peekByte(0) == '+' || l.peekByte(0) == '-' {
+					l.pos++
+				}
+			} else {
+				break
+			}
+		}
+	}
+
+	text := string(l.input[start:l.pos])
+	value, err := parseNumber(text)
+	if err != nil {
+		return token{}, l.errorf("invalid number %s", text)
+	}
+	return token{typ: tokenNumber, text: text, value: value, line: l.line}, nil
+}
+
+func parseNumber(text string) (Value, error) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		if !strings.Contains(text, ".") {
+			v, err := strconv.ParseUint(text[2:], 16, 64)
+			return int64(v), err
+		}
+		return strconv.ParseFloat(text+"p0", 64)
+	}
+
+	if !strings.ContainsAny(text, ".eE") {
+		if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return v, nil
+		}
+	}
+
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lua doesn't distinguish integers from floats, and whole numbers are always written without a fraction, so
+	// treat them as integers to read the same value back after saving
+	if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+		return int64(v), nil
+	}
+	return v, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+// parser builds values from the tokens produced by the lexer.
+type parser struct {
+	lexer *lexer
+	tok   token
+	ready bool
+	// comments collects the comments attached to every token taken since it was last reset.
+	comments []string
+}
+
+func (p *parser) peek() (token, error) {
+	if !p.ready {
+		t, err := p.lexer.next()
+		if err != nil {
+			return token{}, err
+		}
+		p.tok = t
+		p.ready = true
+	}
+	return p.tok, nil
+}
+
+func (p *parser) take() (token, error) {
+	t, err := p.peek()
+	if err != nil {
+		return token{}, err
+	}
+
+	for _, c := range t.comments {
+		p.comments = append(p.comments, c.text)
+	}
+	p.ready = false
+	return t, nil
+}
+
+// takeComments returns the comments collected since the last call, and resets the collection.
+func (p *parser) takeComments() []string {
+	comments := p.comments
+	p.comments = nil
+	return comments
+}
+
+// trailing removes any comments that are on the same line as the last token taken from the next token, and returns
+// them joined together.
+func (p *parser) trailing() (string, error) {
+	if _, err := p.peek(); err != nil {
+		return "", err
+	}
+
+	var texts []string
+	for len(p.tok.comments) > 0 && p.tok.comments[0].sameLine {
+		texts = append(texts, p.tok.comments[0].text)
+		p.tok.comments = p.tok.comments[1:]
+	}
+	return strings.Join(texts, " "), nil
+}
+
+func (p *parser) expect(symbol string) error {
+	t, err := p.take()
+	if err != nil {
+		return err
+	}
+	if t.typ != tokenSymbol || t.text != symbol {
+		return fmt.Errorf("line %d: expected '%s' but found '%s'", t.line, symbol, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseFile() (*File, error) {
+	file := &File{}
+	for {
+		t, err := p.take()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t.typ == tokenEOF:
+			file.Comments = p.takeComments()
+			return file, nil
+		case t.typ == tokenSymbol && t.text == ";":
+			continue
+		case t.typ != tokenName:
+			return nil, fmt.Errorf("line %d: expected variable name but found '%s'", t.line, t.text)
+		}
+
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		v := &Var{Name: t.text, Value: value, Comments: p.takeComments()}
+
+		// A semicolon may end the statement, in which case any comment after it still belongs to this variable
+		if next, err := p.peek(); err != nil {
+			return nil, err
+		} else if next.typ == tokenSymbol && next.text == ";" {
+			_, _ = p.take()
+		}
+
+		if v.Trailing, err = p.trailing(); err != nil {
+			return nil, err
+		}
+		file.Vars = append(file.Vars, v)
+	}
+}
+
+func (p *parser) parseValue() (Value, error) {
+	t, err := p.take()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.typ {
+	case tokenString, tokenNumber:
+		return t.value, nil
+	case tokenName:
+		switch t.text {
+		case "nil":
+			return nil, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	case tokenSymbol:
+		switch t.text {
+		case "{":
+			return p.parseTable()
+		case "-":
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			switch n := v.(type) {
+			case int64:
+				return -n, nil
+			case float64:
+				return -n, nil
+			}
+			return nil, fmt.Errorf("line %d: unexpected '-'", t.line)
+		}
+	}
+	return nil, fmt.Errorf("line %d: unexpected '%s'", t.line, t.text)
+}
+
+func (p *parser) parseTable() (*Table, error) {
+	// Comments inside the table belong to its entries, not whatever the table is assigned to
+	outer := p.takeComments()
+	defer func() {
+		p.comments = outer
+	}()
+
+	table := &Table{}
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if t.typ == tokenSymbol && t.text == "}" {
+			_, _ = p.take()
+			table.Comments = p.takeComments()
+			return table, nil
+		}
+
+		entry := &Entry{}
+		if t.typ == tokenSymbol && t.text == "[" {
+			_, _ = p.take()
+			if entry.Key, err = p.parseValue(); err != nil {
+				return nil, err
+			}
+			if entry.Key == nil {
+				return nil, fmt.Errorf("line %d: table index is nil", t.line)
+			}
+			if f, ok := entry.Key.(float64); ok && math.IsNaN(f) {
+				return nil, fmt.Errorf("line %d: table index is NaN", t.line)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+		} else if t.typ == tokenName && t.text != "nil" && t.text != "true" && t.text != "false" {
+			_, _ = p.take()
+			entry.Key = t.text
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+		}
+
+		if entry.Value, err = p.parseValue(); err != nil {
+			return nil, err
+		}
+		table.Entries = append(table.Entries, entry)
+
+		sep, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if sep.typ == tokenSymbol && (sep.text == "," || sep.text == ";") {
+			_, _ = p.take()
+		} else if sep.typ != tokenSymbol || sep.text != "}" {
+			return nil, fmt.Errorf("line %d: expected ',' or '}' but found '%s'", sep.line, sep.text)
+		}
+
+		entry.Comments = p.takeComments()
+		if entry.Trailing, err = p.trailing(); err != nil {
+			return nil, err
+		}
+		if entry.Key == nil && positionComment.MatchString(entry.Trailing) {
+			// WoW labels positional entries with their index; these are regenerated when writing
+			entry.Trailing = ""
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package savedvars
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// File is a parsed SavedVariables file, consisting of a sequence of global variable assignments.
+type File struct {
+	Vars []*Var
+	// Comments are the comments at the end of the file, after the last assignment.
+	Comments []string
+}
+
+// Var is the assignment of a value to a global variable.
+type Var struct {
+	Name  string
+	Value Value
+	// Comments are the comments that precede the assignment, each on its own line.
+	Comments []string
+	// Trailing is the comment following the assignment on the same line, if any.
+	Trailing string
+}
+
+// Value is a Lua value: nil, bool, string, int64, float64 or *Table.
+type Value interface{}
+
+// Table is a Lua table. Entries are kept in the order they appear in the file.
+type Table struct {
+	Entries []*Entry
+	// Comments are the comments after the last entry, before the closing brace.
+	Comments []string
+}
+
+// Entry is a single field of a table. Key is nil for positional entries (e.g. `{ "a", "b" }`), which are implicitly
+// numbered from 1.
+type Entry struct {
+	Key   Value
+	Value Value
+	// Comments are the comments that precede the entry, each on its own line.
+	Comments []string
+	// Trailing is the comment following the entry on the same line, if any. The index comments WoW adds to
+	// positional entries are not included, as they are regenerated when the table is written.
+	Trailing string
+}
+
+// Parse reads a SavedVariables file from the given reader.
+func Parse(r io.Reader) (*File, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{lexer: newLexer(b)}
+	return p.parseFile()
+}
+
+// Load reads and parses the SavedVariables file at the given path.
+func Load(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return file, nil
+}
+
+// Get returns the value assigned to the given global variable, or nil if it isn't assigned.
+func (f *File) Get(name string) Value {
+	for i := len(f.Vars) - 1; i >= 0; i-- {
+		if f.Vars[i].Name == name {
+			return f.Vars[i].Value
+		}
+	}
+	return nil
+}
+
+// Table returns the table assigned to the given global variable, or nil if it isn't a table.
+func (f *File) Table(name string) *Table {
+	t, _ := f.Get(name).(*Table)
+	return t
+}
+
+// Set assigns a value to the given global variable, replacing any existing assignment. Setting a variable to nil
+// removes it.
+func (f *File) Set(name string, value Value) {
+	for i := len(f.Vars) - 1; i >= 0; i-- {
+		if f.Vars[i].Name == name {
+			if value == nil {
+				f.Vars = append(f.Vars[:i], f.Vars[i+1:]...)
+				continue
+			}
+			f.Vars[i].Value = value
+			return
+		}
+	}
+
+	if value != nil {
+		f.Vars = append(f.Vars, &Var{Name: name, Value: value})
+	}
+}
+
+// Get returns the value stored under the given key, or nil if there isn't one. Integer keys match positional
+// entries as well as explicit ones.
+func (t *Table) Get(key Value) Value {
+	if e := t.find(key); e != nil {
+		return e.Value
+	}
+	return nil
+}
+
+// find returns the entry that determines the value of the given key, or nil if there isn't one.
+func (t *Table) find(key Value) *Entry {
+	if t == nil {
+		return nil
+	}
+
+	var res *Entry
+	t.each(func(k Value, e *Entry) {
+		if k == normaliseKey(key) {
+			// Later entries override earlier ones, as they would in Lua
+			res = e
+		}
+	})
+	return res
+}
+
+// each calls fn for every entry in the table with its normalised key. Positional entries are given their index.
+func (t *Table) each(fn func(key Value, e *Entry)) {
+	var index int64
+	for _, e := range t.Entries {
+		if e.Key == nil {
+			index++
+			fn(index, e)
+		} else {
+			fn(normaliseKey(e.Key), e)
+		}
+	}
+}
+
+// Set stores the value under the given key. An existing entry for the key is updated in place, keeping its position
+// and comments; otherwise a new entry is added to the end of the table. Setting a key to nil deletes it.
+func (t *Table) Set(key Value, value Value) {
+	if value == nil {
+		t.Delete(key)
+		return
+	}
+
+	if e := t.find(key); e != nil {
+		e.Value = value
+		return
+	}
+	t.Entries = append(t.Entries, &Entry{Key: key, Value: value})
+}
+
+// Append adds a positional entry to the end of the table.
+func (t *Table) Append(value Value) {
+	t.Entries = append(t.Entries, &Entry{Value: value})
+}
+
+// Delete removes all entries for the given key. If a positional entry is removed, any positional entries after it are
+// given explicit keys so that their indices don't change.
+func (t *Table) Delete(key Value) {
+	key = normaliseKey(key)
+	var entries []*Entry
+	renumber := false
+	t.each(func(k Value, e *Entry) {
+		switch {
+		case k == key:
+			renumber = renumber || e.Key == nil
+		case renumber && e.Key == nil:
+			e.Key = k
+			entries = append(entries, e)
+		default:
+			entries = append(entries, e)
+		}
+	})
+	t.Entries = entries
+}
+
+// String returns the string stored under the given key, or an empty string if it isn't a string.
+func (t *Table) String(key Value) string {
+	s, _ := t.Get(key).(string)
+	return s
+}
+
+// Int returns the number stored under the given key as an integer, or 0 if it isn't a number.
+func (t *Table) Int(key Value) int64 {
+	switch v := t.Get(key).(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// Bool returns the boolean stored under the given key, or false if it isn't a boolean.
+func (t *Table) Bool(key Value) bool {
+	b, _ := t.Get(key).(bool)
+	return b
+}
+
+// Table returns the table stored under the given key, or nil if it isn't a table.
+func (t *Table) Table(key Value) *Table {
+	v, _ := t.Get(key).(*Table)
+	return v
+}
+
+// Values returns the values of all entries in the table, in order.
+func (t *Table) Values() []Value {
+	if t == nil {
+		return nil
+	}
+
+	var res []Value
+	for _, e := range t.Entries {
+		res = append(res, e.Value)
+	}
+	return res
+}
+
+// normaliseKey converts integral float keys to integers, so that `[1]` and `[1.0]` are treated as the same key.
+func normaliseKey(key Value) Value {
+	switch k := key.(type) {
+	case int:
+		return int64(k)
+	case float64:
+		if k == float64(int64(k)) {
+			return int64(k)
+		}
+	}
+	return key
+}
@@ -0,0 +1,202 @@
+package savedvars
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadFixture(t *testing.T, name string) (*File, []byte) {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Parse(%s) failed: %v", name, err)
+	}
+	return file, b
+}
+
+func TestRoundTrip(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.lua"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no fixtures found: %v", err)
+	}
+
+	for _, fixture := range fixtures {
+		name := filepath.Base(fixture)
+		t.Run(name, func(t *testing.T) {
+			file, _ := loadFixture(t, name)
+
+			written, err := file.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() failed: %v", err)
+			}
+
+			reparsed, err := Parse(bytes.NewReader(written))
+			if err != nil {
+				t.Fatalf("Parse() failed to read written file: %v\n%s", err, written)
+			}
+
+			if !reflect.DeepEqual(file, reparsed) {
+				t.Errorf("file changed after being written and parsed again:\n%s", written)
+			}
+
+			rewritten, err := reparsed.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() failed: %v", err)
+			}
+
+			if !bytes.Equal(written, rewritten) {
+				t.Errorf("writing the file twice gave different results:\n%s\n---\n%s", written, rewritten)
+			}
+		})
+	}
+}
+
+func TestBytes_MatchesWoW(t *testing.T) {
+	for _, name := range []string{"wow.lua", "positional.lua"} {
+		file, original := loadFixture(t, name)
+
+		written, err := file.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes() failed: %v", err)
+		}
+
+		if !bytes.Equal(original, written) {
+			t.Errorf("%s wasn't written back identically:\n%s", name, written)
+		}
+	}
+}
+
+func TestParse_Values(t *testing.T) {
+	file, _ := loadFixture(t, "edited.lua")
+
+	display := file.Table("WeakAurasSaved").Table("displays").Table("Cooldown Tracker")
+	tests := []struct {
+		key  string
+		want Value
+	}{
+		{key: "id", want: "Cooldown Tracker"},
+		{key: "version", want: int64(16)},
+		{key: "alpha", want: 0.75},
+		{key: "ratio", want: 1.5e-3},
+		{key: "scale", want: int64(200)},
+		{key: "note", want: "A long string\nspanning \"lines\" with \\ untouched"},
+		{key: "nested", want: "contains ]] inside"},
+		{key: "escapes", want: "tab\tbell\aquote'hexAdecAjoined unicode \u263a"},
+	}
+	for _, tt := range tests {
+		if got := display.Get(tt.key); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%s) = %#v, want %#v", tt.key, got, tt.want)
+		}
+	}
+
+	if !display.Table("load").Table("class").Table("multi").Bool("PRIEST") {
+		t.Errorf("nested table without brackets wasn't parsed")
+	}
+
+	if got := file.Table("WeakAurasSaved").String(-1); got != "negative key" {
+		t.Errorf("negative key = %q", got)
+	}
+
+	if got := file.Table("WeakAurasSaved").Int("lastUpgrade"); got != 1668211200 {
+		t.Errorf("bracketed key = %d", got)
+	}
+}
+
+func TestParse_Comments(t *testing.T) {
+	file, _ := loadFixture(t, "edited.lua")
+
+	saved := file.Vars[0]
+	if !reflect.DeepEqual(saved.Comments, []string{"-- Tweaked by hand, keep a backup!"}) {
+		t.Errorf("variable comments = %q", saved.Comments)
+	}
+	if saved.Trailing != "-- end of WeakAurasSaved" {
+		t.Errorf("variable trailing comment = %q", saved.Trailing)
+	}
+	if !reflect.DeepEqual(file.Comments, []string{"-- trailing file comment"}) {
+		t.Errorf("file comments = %q", file.Comments)
+	}
+
+	display := file.Table("WeakAurasSaved").Table("displays").Table("Cooldown Tracker")
+	if e := display.find("id"); e == nil || e.Trailing != "-- renamed" {
+		t.Errorf("entry trailing comment = %+v", e)
+	}
+	if !reflect.DeepEqual(display.Comments, []string{"-- a comment before the closing brace"}) {
+		t.Errorf("table comments = %q", display.Comments)
+	}
+
+	var trailing []string
+	for _, e := range display.Table("children").Entries {
+		trailing = append(trailing, e.Trailing)
+	}
+	if !reflect.DeepEqual(trailing, []string{"", "-- the second one", ""}) {
+		t.Errorf("positional comments = %q, want index comments dropped and others kept", trailing)
+	}
+
+	written, err := file.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"\t\t\t\t\"Child One\", -- [1]\n",
+		"\t\t\t\t\"Child Two\", -- the second one\n",
+		"\t\t\t\t\"Child Three\", -- [3]\n",
+		"\t--[[ a block\n\t     comment ]]\n",
+	} {
+		if !strings.Contains(string(written), want) {
+			t.Errorf("written file doesn't contain %q:\n%s", want, written)
+		}
+	}
+}
+
+func TestEdit_PreservesLayout(t *testing.T) {
+	file, original := loadFixture(t, "wow.lua")
+
+	minimap := file.Table("WeakAurasSaved").Table("minimap")
+	minimap.Set("hide", true)
+	minimap.Set("angle", 1.25)
+	file.Table("WeakAurasSaved").Table("displays").Table("Cooldown Tracker").Table("controlledChildren").Delete(int64(1))
+
+	written, err := file.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+
+	want := strings.NewReplacer(
+		"\t\t[\"hide\"] = false,\n", "\t\t[\"hide\"] = true,\n\t\t[\"angle\"] = 1.25,\n",
+		// Deleting a positional entry doesn't renumber the others, so they need explicit keys
+		"\t\t\t\t\"Child One\", -- [1]\n\t\t\t\t\"Child Two\", -- [2]\n\t\t\t\t{", "\t\t\t\t[2] = \"Child Two\",\n\t\t\t\t[3] = {",
+		"}, -- [3]", "},",
+	).Replace(string(original))
+	if string(written) != want {
+		t.Errorf("edited file = \n%s\nwant\n%s", written, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		"Foo = {",
+		"Foo = { [nil] = 1 }",
+		"Foo = \"unfinished",
+		"Foo = [[unfinished",
+		"Foo = \"\\q\"",
+		"Foo = { 1 2 }",
+		"= 1",
+		"Foo = -\"a\"",
+	}
+	for _, input := range tests {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", input)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+-- Tweaked by hand, keep a backup!
+WeakAurasSaved = {
+	-- Displays live here
+	displays = {
+		['Cooldown Tracker'] = {
+			id = 'Cooldown Tracker'; -- renamed
+			version = 0x10,
+			alpha = .75,
+			ratio = 1.5E-3,
+			scale = 2e2,
+			load = { class = { multi = { PRIEST = true, MAGE = false } } },
+			-- positional entries with hand-written labels
+			children = {
+				"Child One", -- [1]
+				"Child Two", -- the second one
+				"Child Three",  --   [3]
+			},
+			note = [[
+A long string
+spanning "lines" with \ untouched]],
+			nested = [==[contains ]] inside]==],
+			escapes = "tab\tbell\aquote\'hex\x41dec\065\z
+			           joined unicode \u{263A}",
+			-- a comment before the closing brace
+		},
+	},
+	--[[ a block
+	     comment ]]
+	["lastUpgrade"] = 1668211200,
+	[-1] = "negative key",
+}; -- end of WeakAurasSaved
+
+PlaterDB = {}
+-- trailing file comment
@@ -0,0 +1,20 @@
+
+BigWigs3DB = {
+	["namespaces"] = {
+		["BigWigs_Plugins_Colors"] = {
+		},
+	},
+	["discardedAddons"] = {
+		"LittleWigs_Classic", -- [1]
+		"LittleWigs_BurningCrusade", -- [2]
+		"LittleWigs_Wrath", -- [3]
+	},
+	["mixed"] = {
+		"first", -- [1]
+		["named"] = "value",
+		"second", -- [2]
+		{
+			"inner", -- [1]
+		}, -- [3]
+	},
+}
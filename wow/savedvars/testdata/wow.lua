@@ -0,0 +1,55 @@
+
+WeakAurasSaved = {
+	["dynamicIconCache"] = {
+	},
+	["displays"] = {
+		["Cooldown Tracker"] = {
+			["id"] = "Cooldown Tracker",
+			["url"] = "https://wago.io/abc123/4",
+			["version"] = 4,
+			["semver"] = "1.0.3",
+			["alpha"] = 0.5,
+			["xOffset"] = -12.25,
+			["yOffset"] = -3,
+			["load"] = {
+				["class"] = {
+					["multi"] = {
+						["PRIEST"] = true,
+						["MAGE"] = false,
+					},
+				},
+			},
+			["controlledChildren"] = {
+				"Child One", -- [1]
+				"Child Two", -- [2]
+				{
+					["nested"] = true,
+				}, -- [3]
+			},
+			["desc"] = "Line one\nLine two with \"quotes\" and a \\ backslash",
+			["customText"] = "function()\n\treturn \"%p\"\nend",
+		},
+	},
+	["lastUpgrade"] = 1668211200,
+	["bigNumber"] = 1e+20,
+	["minimap"] = {
+		["hide"] = false,
+	},
+	[1] = "one",
+	[2.5] = "two and a half",
+	[true] = "yes",
+}
+PlaterDB = {
+	["profiles"] = {
+		["Default"] = {
+			["url"] = "https://wago.io/plater/7",
+			["script_data"] = {
+				{
+					["Name"] = "Cast Bar Icon",
+					["url"] = "https://wago.io/castbar/2",
+				}, -- [1]
+			},
+		},
+	},
+}
+WeakAurasArchive = nil
@@ -0,0 +1,198 @@
+package savedvars
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WriteTo writes the file in the same format that WoW uses, preserving the order of variables and table entries,
+// and any comments.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	b, err := f.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// Bytes returns the contents of the file in the same format that WoW uses.
+func (f *File) Bytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('\n')
+	for _, v := range f.Vars {
+		writeComments(buf, v.Comments, 0)
+		buf.WriteString(v.Name)
+		buf.WriteString(" = ")
+		if err := writeValue(buf, v.Value, 0); err != nil {
+			return nil, fmt.Errorf("%s: %v", v.Name, err)
+		}
+		writeTrailing(buf, v.Trailing)
+		buf.WriteByte('\n')
+	}
+	writeComments(buf, f.Comments, 0)
+	return buf.Bytes(), nil
+}
+
+// Save writes the file to the given path. The contents are written to a temporary file first, so the existing file
+// is left untouched if anything goes wrong.
+func (f *File) Save(path string) error {
+	b, err := f.Bytes()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s-", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Format returns the Lua representation of a single value, formatted as it would be in a SavedVariables file.
+func Format(v Value) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := writeValue(buf, v, 0); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func writeValue(buf *bytes.Buffer, v Value, depth int) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("nil")
+	case bool:
+		buf.WriteString(strconv.FormatBool(val))
+	case string:
+		buf.WriteString(Quote(val))
+	case int:
+		buf.WriteString(strconv.Itoa(val))
+	case int64:
+		buf.WriteString(strconv.FormatInt(val, 10))
+	case float64:
+		s, err := formatFloat(val)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case *Table:
+		return writeTable(buf, val, depth)
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+	return nil
+}
+
+// writeTable writes the table with each entry on its own line, indented with tabs, and explicit keys in brackets.
+// Positional entries are followed by a comment giving their index, as WoW does, unless they have a comment of
+// their own.
+func writeTable(buf *bytes.Buffer, t *Table, depth int) error {
+	indent := strings.Repeat("\t", depth+1)
+	buf.WriteString("{\n")
+
+	var index int64
+	for _, e := range t.Entries {
+		writeComments(buf, e.Comments, depth+1)
+		buf.WriteString(indent)
+		if e.Key != nil {
+			buf.WriteByte('[')
+			if err := writeValue(buf, e.Key, depth+1); err != nil {
+				return err
+			}
+			buf.WriteString("] = ")
+		}
+
+		if err := writeValue(buf, e.Value, depth+1); err != nil {
+			return err
+		}
+		buf.WriteByte(',')
+
+		if e.Key == nil {
+			index++
+		}
+		if e.Key == nil && e.Trailing == "" {
+			writeTrailing(buf, fmt.Sprintf("-- [%d]", index))
+		} else {
+			writeTrailing(buf, e.Trailing)
+		}
+		buf.WriteByte('\n')
+	}
+
+	writeComments(buf, t.Comments, depth+1)
+	buf.WriteString(indent[1:])
+	buf.WriteByte('}')
+	return nil
+}
+
+func writeComments(buf *bytes.Buffer, comments []string, depth int) {
+	for _, c := range comments {
+		buf.WriteString(strings.Repeat("\t", depth))
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+}
+
+func writeTrailing(buf *bytes.Buffer, comment string) {
+	if comment != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(comment)
+	}
+}
+
+// formatFloat formats a number using the shortest representation that will be read back as the same value.
+func formatFloat(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("unsupported number %v", f)
+	}
+
+	if abs := math.Abs(f); abs == 0 || (abs >= 1e-4 && abs < 1e15) {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
+}
+
+// Quote returns a double-quoted Lua string literal representing s.
+func Quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				// Pad to three digits so a following digit isn't read as part of the escape
+				sb.WriteString(fmt.Sprintf("\\%03d", c))
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package toc
+
+// Compatibility describes how an addon's supported interface versions compare to those of a game client.
+type Compatibility string
+
+const (
+	// CompatibilityUnknown indicates the addon doesn't specify an interface version.
+	CompatibilityUnknown Compatibility = "unknown"
+	// CompatibilityCurrent indicates the addon supports the client's interface version.
+	CompatibilityCurrent Compatibility = "current"
+	// CompatibilityOutdated indicates the addon was written for an older client, and the game will mark it out of
+	// date.
+	CompatibilityOutdated Compatibility = "outdated"
+	// CompatibilityNewer indicates the addon was written for a newer client than the one given, such as an upcoming
+	// patch.
+	CompatibilityNewer Compatibility = "newer"
+)
+
+// Compatibility compares the interface versions the addon supports with the given client interface version. If the
+// addon lists several versions (e.g. for different flavours of the game), the closest version that isn't newer than
+// the client is used.
+func (t *Toc) Compatibility(client int) Compatibility {
+	best := t.ClosestInterface(client)
+	switch {
+	case best == 0:
+		return CompatibilityUnknown
+	case best == client:
+		return CompatibilityCurrent
+	case best < client:
+		return CompatibilityOutdated
+	default:
+		return CompatibilityNewer
+	}
+}
+
+// ClosestInterface returns the highest interface version supported by the addon that isn't newer than the given
+// client version. If they are all newer, the lowest is returned. Zero is returned if the addon doesn't specify any.
+func (t *Toc) ClosestInterface(client int) int {
+	best := 0
+	for _, v := range t.Interface {
+		switch {
+		case best == 0:
+			best = v
+		case v <= client && (best > client || v > best):
+			best = v
+		case v > client && best > client && v < best:
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,201 @@
+package toc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/csmith/wadman/wow"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// flavourSuffixes lists the suffixes that may be added to TOC file names to target a flavour of the game, in order of
+// preference. A TOC file without a suffix is used if none of these exist.
+var flavourSuffixes = map[wow.Flavour][]string{
+	wow.FlavourRetail:         {"_Mainline", "-Mainline"},
+	wow.FlavourClassic:        {"_Vanilla", "-Classic", "_Classic"},
+	wow.FlavourBurningCrusade: {"_TBC", "-BCC", "_BCC"},
+	wow.FlavourWrath:          {"_Wrath", "-WOTLKC", "_WOTLKC"},
+}
+
+// escapePattern matches the UI escape sequences that may be used in titles and notes, such as colours and textures.
+var escapePattern = regexp.MustCompile(`\|c[0-9a-fA-F]{8}|\|r|\|T[^|]*\|t|\|A[^|]*\|a`)
+
+// Toc is the parsed contents of an addon's TOC file.
+type Toc struct {
+	// Path is the location of the TOC file.
+	Path string
+	// Fields contains every meta-data field, keyed on its lower-cased name (including any locale suffix such as
+	// "title-dede"). Values are exactly as they appear in the file.
+	Fields map[string]string
+	// Interface contains the versions of the game the addon supports, e.g. 100002.
+	Interface []int
+	// Title is the name of the addon, with any colour codes or textures removed.
+	Title string
+	// Notes is the description of the addon, with any colour codes or textures removed.
+	Notes        string
+	Author       string
+	Version      string
+	Dependencies []string
+	OptionalDeps []string
+	LoadOnDemand bool
+	// SavedVariables and SavedVariablesPerCharacter list the global variables the game saves for the addon.
+	SavedVariables             []string
+	SavedVariablesPerCharacter []string
+	// Files lists the files that the game loads for the addon, in order.
+	Files []string
+}
+
+// Field returns the raw value of the given meta-data field, or an empty string if it isn't present. Field names are
+// case-insensitive.
+func (t *Toc) Field(name string) string {
+	return t.Fields[strings.ToLower(name)]
+}
+
+// LocalisedTitle returns the title of the addon in the given locale (e.g. "deDE"), falling back to the default title
+// if there isn't a translation.
+func (t *Toc) LocalisedTitle(locale string) string {
+	return t.localised("title", locale, t.Title)
+}
+
+// LocalisedNotes returns the description of the addon in the given locale (e.g. "deDE"), falling back to the default
+// description if there isn't a translation.
+func (t *Toc) LocalisedNotes(locale string) string {
+	return t.localised("notes", locale, t.Notes)
+}
+
+func (t *Toc) localised(field string, locale string, fallback string) string {
+	if v, ok := t.Fields[strings.ToLower(fmt.Sprintf("%s-%s", field, locale))]; ok {
+		return StripEscapes(v)
+	}
+	return fallback
+}
+
+// InterfaceString returns the supported interface versions as a comma-separated list.
+func (t *Toc) InterfaceString() string {
+	return FormatInterface(t.Interface)
+}
+
+// FormatInterface formats interface versions as a comma-separated list, in the same way as they are given in a TOC
+// file.
+func FormatInterface(versions []int) string {
+	var parts []string
+	for _, v := range versions {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Parse reads a TOC file from the given reader. Byte order marks and both Unix and Windows line endings are
+// accepted.
+func Parse(r io.Reader) (*Toc, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	toc := &Toc{Fields: make(map[string]string)}
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xEF\xBB\xBF"))))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "##") {
+			parts := strings.SplitN(strings.TrimPrefix(line, "##"), ":", 2)
+			if len(parts) == 2 {
+				toc.Fields[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+			}
+		} else if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			toc.Files = append(toc.Files, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, v := range splitList(toc.Fields["interface"]) {
+		if i, err := strconv.Atoi(v); err == nil {
+			toc.Interface = append(toc.Interface, i)
+		}
+	}
+
+	toc.Title = StripEscapes(toc.Fields["title"])
+	toc.Notes = StripEscapes(toc.Fields["notes"])
+	toc.Author = toc.Fields["author"]
+	toc.Version = toc.Fields["version"]
+	toc.LoadOnDemand = toc.Fields["loadondemand"] == "1"
+	toc.OptionalDeps = splitList(toc.Fields["optionaldeps"])
+	toc.SavedVariables = splitList(toc.Fields["savedvariables"])
+	toc.SavedVariablesPerCharacter = splitList(toc.Fields["savedvariablespercharacter"])
+
+	var keys []string
+	for key := range toc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "requireddeps" || strings.HasPrefix(key, "dep") {
+			toc.Dependencies = append(toc.Dependencies, splitList(toc.Fields[key])...)
+		}
+	}
+	return toc, nil
+}
+
+// Load reads and parses the TOC file at the given path.
+func Load(path string) (*Toc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	toc, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	toc.Path = path
+	return toc, nil
+}
+
+// Find returns the path of the TOC file the game would load for the addon in the given directory when playing the
+// given flavour. Flavour-specific files (such as `Addon_Mainline.toc`) are preferred over `Addon.toc`.
+func Find(dir string, flavour wow.Flavour) (string, error) {
+	name := filepath.Base(dir)
+	for _, suffix := range append(flavourSuffixes[flavour], "") {
+		path := filepath.Join(dir, fmt.Sprintf("%s%s.toc", name, suffix))
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no TOC file found for %s", name)
+}
+
+// ForAddon reads the TOC file for the named addon in the given install, picking the file that matches the
+// install's flavour.
+func ForAddon(install *wow.Install, addon string) (*Toc, error) {
+	path, err := Find(install.AddonPath(addon), install.Flavour())
+	if err != nil {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// StripEscapes removes colour codes, textures and atlas icons from the given text, as they would not be displayed
+// outside of the game.
+func StripEscapes(text string) string {
+	return strings.ReplaceAll(escapePattern.ReplaceAllString(text, ""), "||", "|")
+}
+
+func splitList(value string) []string {
+	var res []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			res = append(res, part)
+		}
+	}
+	return res
+}
@@ -0,0 +1,206 @@
+package toc
+
+import (
+	"github.com/csmith/wadman/wow"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *Toc
+	}{
+		{
+			name:  "unix line endings",
+			input: "## Interface: 100002\n## Title: Addon\n## Author: Someone\n# A comment\n\nCore.lua\n",
+			want: &Toc{
+				Fields:    map[string]string{"interface": "100002", "title": "Addon", "author": "Someone"},
+				Interface: []int{100002},
+				Title:     "Addon",
+				Author:    "Someone",
+				Files:     []string{"Core.lua"},
+			},
+		},
+		{
+			name:  "byte order mark and windows line endings",
+			input: "\xEF\xBB\xBF## Interface: 100002\r\n## Title: Addon  \r\nCore.lua\r\nLocales\\enUS.lua\r\n",
+			want: &Toc{
+				Fields:    map[string]string{"interface": "100002", "title": "Addon"},
+				Interface: []int{100002},
+				Title:     "Addon",
+				Files:     []string{"Core.lua", "Locales\\enUS.lua"},
+			},
+		},
+		{
+			name:  "multiple interface versions",
+			input: "## Interface: 100002, 30400 ,11403,\n## Title: Addon\n",
+			want: &Toc{
+				Fields:    map[string]string{"interface": "100002, 30400 ,11403,", "title": "Addon"},
+				Interface: []int{100002, 30400, 11403},
+				Title:     "Addon",
+			},
+		},
+		{
+			name: "dependencies and saved variables",
+			input: strings.Join([]string{
+				"## Title: |cff00ff00Addon|r",
+				"## Dependencies: Lib1, Lib2",
+				"## RequiredDeps: Lib3",
+				"## OptionalDeps: Lib4",
+				"## LoadOnDemand: 1",
+				"## SavedVariables: AddonDB",
+				"## SavedVariablesPerCharacter: AddonCharDB, AddonCharOptions",
+				"",
+			}, "\n"),
+			want: &Toc{
+				Fields: map[string]string{
+					"title":                      "|cff00ff00Addon|r",
+					"dependencies":               "Lib1, Lib2",
+					"requireddeps":               "Lib3",
+					"optionaldeps":               "Lib4",
+					"loadondemand":               "1",
+					"savedvariables":             "AddonDB",
+					"savedvariablespercharacter": "AddonCharDB, AddonCharOptions",
+				},
+				Title:                      "Addon",
+				Dependencies:               []string{"Lib1", "Lib2", "Lib3"},
+				OptionalDeps:               []string{"Lib4"},
+				LoadOnDemand:               true,
+				SavedVariables:             []string{"AddonDB"},
+				SavedVariablesPerCharacter: []string{"AddonCharDB", "AddonCharOptions"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := Parse(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%s: Parse() failed: %v", tt.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Parse() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocalisedTitle(t *testing.T) {
+	toc, err := Parse(strings.NewReader("## Title: Addon\n## Title-deDE: |cffff0000Erweiterung|r\n## Notes: Does things\n"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	tests := []struct {
+		locale string
+		title  string
+		notes  string
+	}{
+		{locale: "deDE", title: "Erweiterung", notes: "Does things"},
+		{locale: "dede", title: "Erweiterung", notes: "Does things"},
+		{locale: "frFR", title: "Addon", notes: "Does things"},
+	}
+	for _, tt := range tests {
+		if got := toc.LocalisedTitle(tt.locale); got != tt.title {
+			t.Errorf("LocalisedTitle(%s) = %q, want %q", tt.locale, got, tt.title)
+		}
+
+		if got := toc.LocalisedNotes(tt.locale); got != tt.notes {
+			t.Errorf("LocalisedNotes(%s) = %q, want %q", tt.locale, got, tt.notes)
+		}
+	}
+}
+
+func TestStripEscapes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Plain", want: "Plain"},
+		{input: "|cff00ccffDeadly|r Boss Mods", want: "Deadly Boss Mods"},
+		{input: "|CFF00CCFFDeadly|r Boss Mods", want: "|CFF00CCFFDeadly Boss Mods"},
+		{input: "|TInterface\\Icons\\Spell_Nature_Polymorph:16|t Addon", want: " Addon"},
+		{input: "|A:raceicon-orc-male:16:16|a Addon", want: " Addon"},
+		{input: "A || B", want: "A | B"},
+	}
+	for _, tt := range tests {
+		if got := StripEscapes(tt.input); got != tt.want {
+			t.Errorf("StripEscapes(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		flavour wow.Flavour
+		want    string
+	}{
+		{name: "unsuffixed only", files: []string{"Addon.toc"}, flavour: wow.FlavourRetail, want: "Addon.toc"},
+		{name: "mainline", files: []string{"Addon.toc", "Addon_Mainline.toc"}, flavour: wow.FlavourRetail, want: "Addon_Mainline.toc"},
+		{name: "mainline not used for classic", files: []string{"Addon.toc", "Addon_Mainline.toc"}, flavour: wow.FlavourClassic, want: "Addon.toc"},
+		{name: "vanilla preferred", files: []string{"Addon-Classic.toc", "Addon_Vanilla.toc"}, flavour: wow.FlavourClassic, want: "Addon_Vanilla.toc"},
+		{name: "legacy classic", files: []string{"Addon.toc", "Addon-Classic.toc"}, flavour: wow.FlavourClassic, want: "Addon-Classic.toc"},
+		{name: "wrath", files: []string{"Addon.toc", "Addon_Vanilla.toc", "Addon_Wrath.toc"}, flavour: wow.FlavourWrath, want: "Addon_Wrath.toc"},
+		{name: "burning crusade", files: []string{"Addon.toc", "Addon-BCC.toc"}, flavour: wow.FlavourBurningCrusade, want: "Addon-BCC.toc"},
+		{name: "unknown flavour", files: []string{"Addon.toc", "Addon_Mainline.toc"}, flavour: wow.FlavourUnknown, want: "Addon.toc"},
+		{name: "flavour specific only", files: []string{"Addon_Vanilla.toc"}, flavour: wow.FlavourRetail},
+	}
+	for _, tt := range tests {
+		dir := filepath.Join(t.TempDir(), "Addon")
+		if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, f := range tt.files {
+			if err := ioutil.WriteFile(filepath.Join(dir, f), []byte("## Title: Addon\n"), os.FileMode(0644)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := Find(dir, tt.flavour)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: Find() = %s, want error", tt.name, got)
+			}
+			continue
+		}
+
+		if err != nil || got != filepath.Join(dir, tt.want) {
+			t.Errorf("%s: Find() = %s, %v, want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestForAddon(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "_classic_era_")
+	dir := filepath.Join(base, "Interface", "AddOns", "Addon")
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"Addon.toc":         "## Interface: 100002\n## Title: Retail\n",
+		"Addon_Vanilla.toc": "## Interface: 11403\n## Title: Classic\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), os.FileMode(0644)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ForAddon(wow.NewWowInstall(base), "Addon")
+	if err != nil {
+		t.Fatalf("ForAddon() failed: %v", err)
+	}
+
+	if got.Title != "Classic" || !reflect.DeepEqual(got.Interface, []int{11403}) || got.Path != filepath.Join(dir, "Addon_Vanilla.toc") {
+		t.Errorf("ForAddon() = %+v, want the classic TOC file", got)
+	}
+}
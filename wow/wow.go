@@ -2,8 +2,10 @@ package wow
 
 import (
 	"archive/zip"
-	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -53,6 +55,11 @@ func (w *Install) ListAddons() ([]string, error) {
 	for i := range fs {
 		if fs[i].IsDir() {
 			folders = append(folders, fs[i].Name())
+		} else if fs[i].Mode()&os.ModeSymlink != 0 {
+			// Addons installed from local directories may be symlinked
+			if info, err := os.Stat(filepath.Join(w.addonsPath, fs[i].Name())); err == nil && info.IsDir() {
+				folders = append(folders, fs[i].Name())
+			}
 		}
 	}
 
@@ -61,6 +68,9 @@ func (w *Install) ListAddons() ([]string, error) {
 
 // ReadToc reads the TOC file associated with the given addon and returns the meta-data and included files.
 // Meta-data keys will be lower-cased.
+//
+// Deprecated: ReadToc only reads the addon's unsuffixed TOC file. Use toc.ForAddon instead, which picks the file the
+// game would load for the install's flavour.
 func (w *Install) ReadToc(addon string) (metadata map[string]string, files []string, err error) {
 	p := filepath.Join(w.addonsPath, addon, fmt.Sprintf("%s.toc", addon))
 	b, err := ioutil.ReadFile(p)
@@ -99,21 +109,108 @@ func (w *Install) RemoveAddons(names []string) error {
 	return nil
 }
 
+// ProgressFunc is called periodically while a file is being downloaded, with the number of bytes downloaded so far
+// and the total size of the file (or -1 if it is unknown).
+type ProgressFunc func(downloaded int64, total int64)
+
 // InstallAddonFromUrl downloads a ZIP file from the given URL and deploys it to the WoW addons directory, returning a
-// slice of top-level folder names that were created.
-func (w *Install) InstallAddonFromUrl(url string) ([]string, error) {
-	res, err := http.Get(url)
+// slice of top-level folder names that were created and the SHA-256 checksum of the ZIP file. If expectedChecksum is
+// non-empty the download is verified against it before anything is deployed.
+func (w *Install) InstallAddonFromUrl(ctx context.Context, url string, expectedChecksum string, progress ProgressFunc) ([]string, string, error) {
+	b, checksum, err := DownloadAddon(ctx, url, expectedChecksum, progress)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	dirs, err := w.InstallAddon(ctx, bytes.NewReader(b))
+	return dirs, checksum, err
+}
+
+// DownloadAddon downloads a ZIP file from the given URL, returning its contents and SHA-256 checksum. If
+// expectedChecksum is non-empty and doesn't match the downloaded file an error is returned. If progress is non-nil
+// it is called as the file is downloaded.
+func DownloadAddon(ctx context.Context, url string, expectedChecksum string, progress ProgressFunc) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return DownloadRequest(http.DefaultClient, req, expectedChecksum, progress)
+}
+
+// DownloadRequest performs the given request using the given client to download a ZIP file, for sources that need to
+// add authentication or other headers. If client is nil http.DefaultClient is used. It otherwise behaves the same as
+// DownloadAddon.
+func DownloadRequest(client *http.Client, req *http.Request, expectedChecksum string, progress ProgressFunc) ([]byte, string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	url := req.URL.String()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
 	}
 
 	defer res.Body.Close()
-	return w.InstallAddon(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unable to download %s: %s", url, res.Status)
+	}
+
+	var body io.Reader = res.Body
+	if progress != nil {
+		body = &progressReader{reader: res.Body, total: res.ContentLength, progress: progress}
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	checksum := Checksum(b)
+	if expectedChecksum != "" && !strings.EqualFold(checksum, expectedChecksum) {
+		return nil, "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", url, expectedChecksum, checksum)
+	}
+
+	return b, checksum, nil
+}
+
+// progressReader wraps a reader and reports how many bytes have been read from it.
+type progressReader struct {
+	reader   io.Reader
+	read     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.progress(p.read, p.total)
+	}
+	return n, err
+}
+
+// Checksum returns the hex-encoded SHA-256 checksum of the given data.
+func Checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
 }
 
 // InstallAddon reads a ZIP file from the given reader and deploys it to the WoW addons directory, returning a
 // slice of top-level folder names that were created.
-func (w *Install) InstallAddon(r io.Reader) ([]string, error) {
+func (w *Install) InstallAddon(ctx context.Context, r io.Reader) ([]string, error) {
+	return w.ReplaceAddons(ctx, r, nil)
+}
+
+// ReplaceAddons reads a ZIP file from the given reader and deploys it to the WoW addons directory in place of the
+// given existing addons, returning a slice of top-level folder names that were created.
+//
+// The ZIP file is extracted to a staging directory first. If the context is cancelled or an error occurs during
+// extraction, the existing addons are left untouched. Once extraction has finished the directories are swapped into
+// place without checking the context again, and if that fails the original directories are restored.
+func (w *Install) ReplaceAddons(ctx context.Context, r io.Reader, old []string) ([]string, error) {
 	b, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
@@ -124,16 +221,97 @@ func (w *Install) InstallAddon(r io.Reader) ([]string, error) {
 		return nil, err
 	}
 
+	if err := os.MkdirAll(w.addonsPath, os.FileMode(0755)); err != nil {
+		return nil, err
+	}
+
+	// Stage next to the addons directory so the final renames don't cross file systems
+	staging, err := ioutil.TempDir(filepath.Dir(w.addonsPath), ".wadman-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	dirs, err := extract(ctx, reader, filepath.Join(staging, "new"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.swapAddons(filepath.Join(staging, "new"), filepath.Join(staging, "old"), dirs, old); err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// swapAddons moves the directories named in replaced out of the addons directory into the backup directory, then
+// moves the directories named in dirs from the source directory into the addons directory. Directories that would
+// be overwritten are also backed up. If anything fails, the backed up directories are restored.
+func (w *Install) swapAddons(source, backup string, dirs []string, replaced []string) (err error) {
+	if err := os.MkdirAll(backup, os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	var backedUp, installed []string
+	defer func() {
+		if err != nil {
+			for _, d := range installed {
+				_ = os.RemoveAll(filepath.Join(w.addonsPath, d))
+			}
+			for _, d := range backedUp {
+				_ = os.Rename(filepath.Join(backup, d), filepath.Join(w.addonsPath, d))
+			}
+		}
+	}()
+
+	seen := make(map[string]bool)
+	for _, d := range append(append([]string{}, replaced...), dirs...) {
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+
+		if _, statErr := os.Stat(filepath.Join(w.addonsPath, d)); os.IsNotExist(statErr) {
+			continue
+		}
+
+		if err = os.Rename(filepath.Join(w.addonsPath, d), filepath.Join(backup, d)); err != nil {
+			return err
+		}
+		backedUp = append(backedUp, d)
+	}
+
+	for _, d := range dirs {
+		if err = os.Rename(filepath.Join(source, d), filepath.Join(w.addonsPath, d)); err != nil {
+			return err
+		}
+		installed = append(installed, d)
+	}
+
+	return nil
+}
+
+// extract writes the contents of the ZIP file to the target directory, returning a slice of top-level folder names
+// that were created. The context is checked before each file is extracted.
+func extract(ctx context.Context, reader *zip.Reader, target string) ([]string, error) {
 	dirs := make(map[string]bool)
 
 	for i := range reader.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		err := func(f *zip.File) error {
+			name := filepath.FromSlash(f.Name)
+			if strings.HasPrefix(filepath.Clean(name), "..") || filepath.IsAbs(name) {
+				return fmt.Errorf("invalid path in zip file: %s", f.Name)
+			}
+
 			parts := strings.Split(f.Name, "/")
 			dirs[parts[0]] = true
 
-			target := filepath.Join(w.addonsPath, f.Name)
+			path := filepath.Join(target, name)
 			if f.FileInfo().IsDir() {
-				return os.MkdirAll(target, os.FileMode(0755))
+				return os.MkdirAll(path, os.FileMode(0755))
 			} else {
 				in, err := f.Open()
 				if err != nil {
@@ -141,8 +319,8 @@ func (w *Install) InstallAddon(r io.Reader) ([]string, error) {
 				}
 				defer in.Close()
 
-				_ = os.MkdirAll(filepath.Dir(target), os.FileMode(0755))
-				out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+				_ = os.MkdirAll(filepath.Dir(path), os.FileMode(0755))
+				out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
 				if err != nil {
 					return err
 				}
@@ -178,40 +356,3 @@ func (w *Install) HasAddons(names []string) bool {
 	}
 	return true
 }
-
-// DisabledAddons returns a map of addons that are disabled in the WoW client.
-func (w *Install) DisabledAddons() (map[string]bool, error) {
-	matches, err := filepath.Glob(filepath.Join(w.path, "WTF", "Account", "*", "*", "*", "AddOns.txt"))
-	if err != nil {
-		return nil, err
-	}
-
-	disabled := make(map[string]bool)
-	for i := range matches {
-		err := func(path string) error {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-
-			defer file.Close()
-
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				if strings.HasSuffix(line, ": disabled") {
-					name := strings.TrimSuffix(line, ": disabled")
-					disabled[name] = true
-				}
-			}
-
-			return nil
-		}(matches[i])
-
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return disabled, nil
-}
@@ -0,0 +1,83 @@
+package wowi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type FileDetails struct {
+	Id           int      `json:"id"`
+	Version      string   `json:"version"`
+	Checksum     string   `json:"checksum"`
+	Url          string   `json:"downloadUri"`
+	Title        string   `json:"title"`
+	Author       string   `json:"author"`
+	Description  string   `json:"description"`
+	Changelog    string   `json:"changelog"`
+	LastUpdate   int64    `json:"lastUpdate"`
+	Downloads    int64    `json:"downloads"`
+	GameVersions []string `json:"gameVersions"`
+}
+
+type ListedFile struct {
+	Id           int      `json:"id"`
+	CategoryId   int      `json:"categoryId"`
+	Version      string   `json:"version"`
+	LastUpdate   int64    `json:"lastUpdate"`
+	Title        string   `json:"title"`
+	Author       string   `json:"author"`
+	Downloads    int64    `json:"downloads"`
+	GameVersions []string `json:"gameVersions"`
+}
+
+// Category is a section of WoW Interface that addons are listed in.
+type Category struct {
+	Id    json.Number `json:"id"`
+	Title string      `json:"title"`
+}
+
+// GetFileDetails retrieves the details of the current file for the given addon.
+func GetFileDetails(ctx context.Context, id int) (*FileDetails, error) {
+	var response []FileDetails
+	if err := get(ctx, fmt.Sprintf("https://api.mmoui.com/v4/game/WOW/filedetails/%d.json", id), &response); err != nil {
+		return nil, err
+	}
+
+	if len(response) != 1 {
+		return nil, fmt.Errorf("expected 1 result, got %d", len(response))
+	}
+
+	return &response[0], nil
+}
+
+// GetFileList retrieves the list of every addon available on WoW Interface.
+func GetFileList(ctx context.Context) ([]ListedFile, error) {
+	var response []ListedFile
+	err := get(ctx, "https://api.mmoui.com/v4/game/WOW/filelist.json", &response)
+	return response, err
+}
+
+// GetCategoryList retrieves the categories that addons on WoW Interface are listed in.
+func GetCategoryList(ctx context.Context) ([]Category, error) {
+	var response []Category
+	err := get(ctx, "https://api.mmoui.com/v4/game/WOW/categorylist.json", &response)
+	return response, err
+}
+
+// get performs a HTTP GET request for the given URL and decodes the JSON response into target.
+func get(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(target)
+}
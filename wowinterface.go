@@ -1,14 +1,20 @@
 package wadman
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/csmith/wadman/wow"
-	"io"
-	"net/http"
+	"github.com/csmith/wadman/wowi"
+	"strconv"
+	"strings"
 	"time"
 )
 
+func init() {
+	RegisterProvider(&wowInterfaceProvider{})
+}
+
 type WowInterfaceAddon struct {
 	BaseAddon
 	Id           int    `json:"id"`
@@ -17,7 +23,7 @@ type WowInterfaceAddon struct {
 }
 
 func NewWowInterfaceAddon(id int) Addon {
-	return &WowInterfaceAddon{BaseAddon: BaseAddon{Type: TypeWowInterface}, Id: id}
+	return &WowInterfaceAddon{BaseAddon: BaseAddon{AddonType: TypeWowInterface}, Id: id}
 }
 
 func (w *WowInterfaceAddon) DisplayName() string {
@@ -28,44 +34,191 @@ func (w *WowInterfaceAddon) ShortName() string {
 	return fmt.Sprintf("wowi:%d", w.Id)
 }
 
-func (w *WowInterfaceAddon) Update(install *wow.Install, _ io.Writer, force bool) (updated bool, err error) {
-	var response []struct {
-		Id       int    `json:"id"`
-		Version  string `json:"version"`
-		Checksum string `json:"checksum"`
-		Url      string `json:"downloadUri"`
-		Title    string `json:"title"`
+// Locked returns a lockfile entry for the installed file. WoW Interface doesn't expose file IDs, so the checksum
+// reported by its API is used to identify the file instead.
+func (w *WowInterfaceAddon) Locked() LockEntry {
+	return w.LockEntry(w.ShortName(), w.Title, w.LastChecksum)
+}
+
+func (w *WowInterfaceAddon) Install(ctx context.Context, install *wow.Install, release *Release, progress wow.ProgressFunc) error {
+	if err := w.Deploy(ctx, install, release, progress); err != nil {
+		return err
 	}
 
-	url := fmt.Sprintf("https://api.mmoui.com/v4/game/WOW/filedetails/%d.json", w.Id)
-	res, err := http.Get(url)
+	w.LastChecksum = release.FileId
+	if release.Name != "" {
+		w.Title = release.Name
+	}
+	return nil
+}
+
+type wowInterfaceProvider struct{}
+
+func (p *wowInterfaceProvider) Type() AddonType {
+	return TypeWowInterface
+}
+
+func (p *wowInterfaceProvider) Name() string {
+	return "WoW Interface"
+}
+
+func (p *wowInterfaceProvider) NewAddon(id string) (Addon, error) {
+	target, err := strconv.Atoi(id)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("invalid WoW Interface project ID: %s", id)
 	}
+	return NewWowInterfaceAddon(target), nil
+}
+
+func (p *wowInterfaceProvider) Marshal(addon Addon) ([]byte, error) {
+	return json.Marshal(addon)
+}
 
-	defer res.Body.Close()
-	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-		return false, err
+func (p *wowInterfaceProvider) Unmarshal(data []byte) (Addon, error) {
+	addon := NewWowInterfaceAddon(0)
+	if err := json.Unmarshal(data, addon); err != nil {
+		return nil, err
+	}
+	return addon, nil
+}
+
+// Search finds addons whose title or author contains the query. WoW Interface doesn't have a search API, so this
+// searches the full list of files available.
+func (p *wowInterfaceProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	files, err := wowi.GetFileList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryList, err := wowi.GetCategoryList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get categories: %v", err)
 	}
 
-	if len(response) != 1 {
-		return false, fmt.Errorf("expected 1 result, got %d", len(response))
+	categories := make(map[string]string)
+	for _, c := range categoryList {
+		categories[c.Id.String()] = c.Title
 	}
 
-	w.Title = response[0].Title
-	if w.LastChecksum != response[0].Checksum || force {
-		// New version to install
-		dirs, err := install.InstallAddonFromUrl(response[0].Url)
-		if err != nil {
-			return false, err
+	query = strings.ToLower(query)
+
+	var results []SearchResult
+	for i := range files {
+		f := files[i]
+		if !strings.Contains(strings.ToLower(f.Title), query) && !strings.Contains(strings.ToLower(f.Author), query) {
+			continue
+		}
+
+		result := SearchResult{
+			Id:          fmt.Sprintf("wowi:%d", f.Id),
+			Name:        f.Title,
+			Source:      p.Name(),
+			Author:      f.Author,
+			Downloads:   f.Downloads,
+			LastUpdated: time.Unix(0, f.LastUpdate*int64(time.Millisecond)),
+		}
+
+		if category, ok := categories[strconv.Itoa(f.CategoryId)]; ok {
+			result.Categories = []string{category}
+		}
+
+		seen := make(map[wow.Flavour]bool)
+		for _, v := range f.GameVersions {
+			if flavour := wow.FlavourForVersion(v); flavour != wow.FlavourUnknown && !seen[flavour] {
+				seen[flavour] = true
+				result.Flavours = append(result.Flavours, flavour)
+			}
 		}
 
-		w.LastChecksum = response[0].Checksum
-		w.LastUpdate = time.Now()
-		w.Directories = dirs
-		w.Version = response[0].Version
-		return true, nil
-	} else {
-		return false, nil
+		results = append(results, result)
 	}
+	return results, nil
+}
+
+func (p *wowInterfaceProvider) Resolve(ctx context.Context, addon Addon, _ *wow.Install, observer Observer) (*Release, error) {
+	w, ok := addon.(*WowInterfaceAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a WoW Interface addon", addon.ShortName())
+	}
+
+	details, err := wowi.GetFileDetails(ctx, w.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// WoW Interface doesn't expose file IDs, so the checksum is used to identify the file instead
+	observer.Notify(CandidateEvent{
+		Addon:    addon,
+		FileId:   details.Checksum,
+		Version:  details.Version,
+		Eligible: true,
+		Selected: true,
+
+		FlavourValid:      true,
+		ChannelValid:      true,
+		GameVersionsValid: true,
+	})
+
+	return &Release{
+		FileId:  details.Checksum,
+		Name:    details.Title,
+		Version: details.Version,
+		Url:     details.Url,
+	}, nil
+}
+
+// Details returns information about the addon. WoW Interface only offers the current file for download, so that is
+// the only file returned.
+func (p *wowInterfaceProvider) Details(ctx context.Context, addon Addon) (*AddonDetails, error) {
+	w, ok := addon.(*WowInterfaceAddon)
+	if !ok {
+		return nil, fmt.Errorf("addon %s is not a WoW Interface addon", addon.ShortName())
+	}
+
+	info, err := wowi.GetFileDetails(ctx, w.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	file := File{
+		Release: Release{
+			FileId:  info.Checksum,
+			Name:    info.Title,
+			Version: info.Version,
+			Url:     info.Url,
+			Date:    time.Unix(0, info.LastUpdate*int64(time.Millisecond)),
+		},
+		Channel:      ChannelRelease,
+		GameVersions: info.GameVersions,
+	}
+	if len(info.GameVersions) > 0 {
+		file.Flavour = wow.FlavourForVersion(info.GameVersions[0])
+	}
+
+	return &AddonDetails{
+		Name:        info.Title,
+		Description: info.Description,
+		Authors:     []string{info.Author},
+		Website:     fmt.Sprintf("https://www.wowinterface.com/downloads/info%d", w.Id),
+		Files:       []File{file},
+	}, nil
+}
+
+func (p *wowInterfaceProvider) Changelog(ctx context.Context, addon Addon, release *Release) (string, error) {
+	w, ok := addon.(*WowInterfaceAddon)
+	if !ok {
+		return "", fmt.Errorf("addon %s is not a WoW Interface addon", addon.ShortName())
+	}
+
+	info, err := wowi.GetFileDetails(ctx, w.Id)
+	if err != nil {
+		return "", err
+	}
+
+	// WoW Interface only publishes the changelog for the current file
+	if release.FileId != "" && !strings.EqualFold(release.FileId, info.Checksum) {
+		return "", nil
+	}
+
+	return info.Changelog, nil
 }